@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Template is a named template that can be used with `kli project <name>`
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	URL         string   `json:"url"`
+	Branch      string   `json:"branch,omitempty"`
+	Ref         string   `json:"ref,omitempty"`
+	Subdir      string   `json:"subdir,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Registry is the content of a shared registry file
+type Registry struct {
+	Templates []Template `json:"templates"`
+}
+
+// UserConfig holds the user settings stored in the kli config file
+type UserConfig struct {
+	Templates  []Template `json:"templates,omitempty"`
+	Registries []string   `json:"registries,omitempty"`
+	// TrustedSources lists the template origins whose hooks run without
+	// asking for confirmation
+	TrustedSources []string `json:"trustedSources,omitempty"`
+}
+
+// Path returns the location of the user config file. It can be overridden
+// with the KLI_CONFIG environment variable.
+func Path() (string, error) {
+	if path := os.Getenv("KLI_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kli", "config.json"), nil
+}
+
+// Load reads the user config file. A missing file returns an empty config.
+func Load() (*UserConfig, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	var userConfig UserConfig
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &userConfig, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(payload, &userConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return &userConfig, nil
+}
+
+// Save writes the user config file, creating its directory if needed
+func Save(userConfig *UserConfig) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(userConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(payload, '\n'), 0644)
+}
+
+// CacheDir returns the directory where kli stores downloaded templates. It can
+// be overridden with the KLI_CACHE_DIR environment variable.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("KLI_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kli", "templates"), nil
+}
@@ -1,6 +1,31 @@
 package git
 
+import "regexp"
+
+var commitRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// GitClone describes what to fetch from a repository. Ref may be a branch, a
+// tag or a commit SHA, and Subdir restricts the checkout to a single directory.
 type GitClone struct {
 	Repository string
-	Branch     string
+	// Deprecated: use Ref, which also accepts tags and commits. Branch is
+	// only used when Ref is empty.
+	Branch string
+	Ref    string
+	Subdir string
+}
+
+// Revision returns Ref, or Branch when Ref is empty
+func (c GitClone) Revision() string {
+	if c.Ref != "" {
+		return c.Ref
+	}
+	return c.Branch
+}
+
+// MayBeCommit reports whether the revision looks like a commit SHA. Branches
+// and tags with hex-only names match too, so ShallowClone tries the revision
+// as a branch or tag first.
+func (c GitClone) MayBeCommit() bool {
+	return commitRegex.MatchString(c.Revision())
 }
@@ -1,7 +1,9 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -15,6 +17,12 @@ type Cmd interface {
 	PushTags(verbose bool, tag string) error
 	RemoveTag(verbose bool, tag string) error
 	Clone(repository, branch string, workdir string) error
+	ShallowClone(clone GitClone, workdir string) error
+	MergeFile(current, base, other string) (bool, error)
+	Init(workdir, branch string) error
+	AddAll(workdir string) error
+	Commit(workdir, message string) error
+	AddRemote(workdir, name, url string) error
 }
 
 // GitCmd is a struct that holds the path to the git executable
@@ -142,3 +150,115 @@ func (g *GitCmd) Clone(repository, branch string, workdir string) error {
 	_, err := g.Run(true, "clone", repository, "-b", branch, workdir)
 	return err
 }
+
+// ShallowClone clones a single ref of a repository without its history. When
+// Subdir is set only that directory is checked out using a sparse checkout. A
+// revision that is not a branch or tag but looks like a SHA is fetched as a
+// commit. An empty revision clones the default branch of the remote.
+func (g *GitCmd) ShallowClone(clone GitClone, workdir string) error {
+	revision := clone.Revision()
+	if revision == "" {
+		fmt.Println("Cloning repository", clone.Repository)
+	} else {
+		fmt.Println("Cloning repository", clone.Repository, "at", revision)
+	}
+	args := []string{"clone", "--depth", "1"}
+	if clone.Subdir != "" {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	if revision != "" {
+		args = append(args, "-b", revision)
+	}
+	args = append(args, clone.Repository, workdir)
+	_, err := g.Run(true, args...)
+	if err != nil && clone.MayBeCommit() {
+		err = os.RemoveAll(workdir)
+		if err != nil {
+			return err
+		}
+		return g.cloneCommit(clone, workdir)
+	}
+	if err != nil {
+		return err
+	}
+	if clone.Subdir != "" {
+		_, err = g.Run(true, "-C", workdir, "sparse-checkout", "set", clone.Subdir)
+	}
+	return err
+}
+
+// cloneCommit fetches a single commit, falling back to a full fetch when the
+// remote does not allow fetching commits by SHA
+func (g *GitCmd) cloneCommit(clone GitClone, workdir string) error {
+	_, err := g.Run(true, "init", "-q", workdir)
+	if err != nil {
+		return err
+	}
+	_, err = g.Run(true, "-C", workdir, "remote", "add", "origin", clone.Repository)
+	if err != nil {
+		return err
+	}
+	if clone.Subdir != "" {
+		_, err = g.Run(true, "-C", workdir, "sparse-checkout", "set", clone.Subdir)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = g.Run(true, "-C", workdir, "fetch", "--depth", "1", "--filter=blob:none", "origin", clone.Revision())
+	if err != nil {
+		_, err = g.Run(true, "-C", workdir, "fetch", "--filter=blob:none", "origin")
+		if err != nil {
+			return err
+		}
+		_, err = g.Run(true, "-C", workdir, "checkout", "-q", clone.Revision())
+		return err
+	}
+	_, err = g.Run(true, "-C", workdir, "checkout", "-q", "FETCH_HEAD")
+	return err
+}
+
+// MergeFile runs a three-way merge of the changes between base and other into
+// current, which is updated in place. It returns true when conflicts were
+// found and marked in current.
+func (g *GitCmd) MergeFile(current, base, other string) (bool, error) {
+	cmd := exec.Command(g.Path, "merge-file", "-L", "local", "-L", "base", "-L", "template", current, base, other)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return false, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return true, nil
+	}
+	return false, fmt.Errorf("error running git merge-file: %s: %s", err, strings.TrimSpace(string(out)))
+}
+
+// Init creates an empty repository in workdir whose default branch is branch.
+// HEAD is set with symbolic-ref so it also works with Git versions that do not
+// support init -b.
+func (g *GitCmd) Init(workdir, branch string) error {
+	_, err := g.Run(false, "-C", workdir, "init", "-q")
+	if err != nil {
+		return err
+	}
+	_, err = g.Run(false, "-C", workdir, "symbolic-ref", "HEAD", "refs/heads/"+branch)
+	return err
+}
+
+// AddAll stages every file of the working tree in workdir
+func (g *GitCmd) AddAll(workdir string) error {
+	_, err := g.Run(false, "-C", workdir, "add", "-A")
+	return err
+}
+
+// Commit creates a commit with the staged changes in workdir
+func (g *GitCmd) Commit(workdir, message string) error {
+	_, err := g.Run(false, "-C", workdir, "commit", "-q", "-m", message)
+	return err
+}
+
+// AddRemote adds a remote named name pointing to url in workdir
+func (g *GitCmd) AddRemote(workdir, name, url string) error {
+	_, err := g.Run(false, "-C", workdir, "remote", "add", name, url)
+	return err
+}
@@ -19,6 +19,8 @@ func main() {
 	}
 	rootCommand.AddCommand(semver.NewSemverCommand(gitCmd))
 	rootCommand.AddCommand(project.NewProjectCommand(gitCmd))
+	rootCommand.AddCommand(project.NewTemplateCommand(gitCmd))
+	rootCommand.AddCommand(project.NewGenerateCommand(gitCmd))
 	if err := rootCommand.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
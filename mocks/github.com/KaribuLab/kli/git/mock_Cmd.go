@@ -20,6 +20,92 @@ func (_m *MockCmd) EXPECT() *MockCmd_Expecter {
 	return &MockCmd_Expecter{mock: &_m.Mock}
 }
 
+// AddAll provides a mock function with given fields: workdir
+func (_m *MockCmd) AddAll(workdir string) error {
+	ret := _m.Called(workdir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(workdir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockCmd_AddAll_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddAll'
+type MockCmd_AddAll_Call struct {
+	*mock.Call
+}
+
+// AddAll is a helper method to define mock.On call
+//   - workdir string
+func (_e *MockCmd_Expecter) AddAll(workdir interface{}) *MockCmd_AddAll_Call {
+	return &MockCmd_AddAll_Call{Call: _e.mock.On("AddAll", workdir)}
+}
+
+func (_c *MockCmd_AddAll_Call) Run(run func(workdir string)) *MockCmd_AddAll_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockCmd_AddAll_Call) Return(_a0 error) *MockCmd_AddAll_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCmd_AddAll_Call) RunAndReturn(run func(string) error) *MockCmd_AddAll_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddRemote provides a mock function with given fields: workdir, name, url
+func (_m *MockCmd) AddRemote(workdir string, name string, url string) error {
+	ret := _m.Called(workdir, name, url)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(workdir, name, url)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockCmd_AddRemote_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddRemote'
+type MockCmd_AddRemote_Call struct {
+	*mock.Call
+}
+
+// AddRemote is a helper method to define mock.On call
+//   - workdir string
+//   - name string
+//   - url string
+func (_e *MockCmd_Expecter) AddRemote(workdir interface{}, name interface{}, url interface{}) *MockCmd_AddRemote_Call {
+	return &MockCmd_AddRemote_Call{Call: _e.mock.On("AddRemote", workdir, name, url)}
+}
+
+func (_c *MockCmd_AddRemote_Call) Run(run func(workdir string, name string, url string)) *MockCmd_AddRemote_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockCmd_AddRemote_Call) Return(_a0 error) *MockCmd_AddRemote_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCmd_AddRemote_Call) RunAndReturn(run func(string, string, string) error) *MockCmd_AddRemote_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Clone provides a mock function with given fields: repository, branch, workdir
 func (_m *MockCmd) Clone(repository string, branch string, workdir string) error {
 	ret := _m.Called(repository, branch, workdir)
@@ -64,6 +150,49 @@ func (_c *MockCmd_Clone_Call) RunAndReturn(run func(string, string, string) erro
 	return _c
 }
 
+// Commit provides a mock function with given fields: workdir, message
+func (_m *MockCmd) Commit(workdir string, message string) error {
+	ret := _m.Called(workdir, message)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(workdir, message)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockCmd_Commit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Commit'
+type MockCmd_Commit_Call struct {
+	*mock.Call
+}
+
+// Commit is a helper method to define mock.On call
+//   - workdir string
+//   - message string
+func (_e *MockCmd_Expecter) Commit(workdir interface{}, message interface{}) *MockCmd_Commit_Call {
+	return &MockCmd_Commit_Call{Call: _e.mock.On("Commit", workdir, message)}
+}
+
+func (_c *MockCmd_Commit_Call) Run(run func(workdir string, message string)) *MockCmd_Commit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockCmd_Commit_Call) Return(_a0 error) *MockCmd_Commit_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCmd_Commit_Call) RunAndReturn(run func(string, string) error) *MockCmd_Commit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CurrentBranch provides a mock function with given fields: verbose
 func (_m *MockCmd) CurrentBranch(verbose bool) (string, error) {
 	ret := _m.Called(verbose)
@@ -224,6 +353,103 @@ func (_c *MockCmd_GetTags_Call) RunAndReturn(run func(bool) ([]git.GitTag, error
 	return _c
 }
 
+// Init provides a mock function with given fields: workdir, branch
+func (_m *MockCmd) Init(workdir string, branch string) error {
+	ret := _m.Called(workdir, branch)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(workdir, branch)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockCmd_Init_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Init'
+type MockCmd_Init_Call struct {
+	*mock.Call
+}
+
+// Init is a helper method to define mock.On call
+//   - workdir string
+//   - branch string
+func (_e *MockCmd_Expecter) Init(workdir interface{}, branch interface{}) *MockCmd_Init_Call {
+	return &MockCmd_Init_Call{Call: _e.mock.On("Init", workdir, branch)}
+}
+
+func (_c *MockCmd_Init_Call) Run(run func(workdir string, branch string)) *MockCmd_Init_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockCmd_Init_Call) Return(_a0 error) *MockCmd_Init_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCmd_Init_Call) RunAndReturn(run func(string, string) error) *MockCmd_Init_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MergeFile provides a mock function with given fields: current, base, other
+func (_m *MockCmd) MergeFile(current string, base string, other string) (bool, error) {
+	ret := _m.Called(current, base, other)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, string) (bool, error)); ok {
+		return rf(current, base, other)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, string) bool); ok {
+		r0 = rf(current, base, other)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(current, base, other)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockCmd_MergeFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MergeFile'
+type MockCmd_MergeFile_Call struct {
+	*mock.Call
+}
+
+// MergeFile is a helper method to define mock.On call
+//   - current string
+//   - base string
+//   - other string
+func (_e *MockCmd_Expecter) MergeFile(current interface{}, base interface{}, other interface{}) *MockCmd_MergeFile_Call {
+	return &MockCmd_MergeFile_Call{Call: _e.mock.On("MergeFile", current, base, other)}
+}
+
+func (_c *MockCmd_MergeFile_Call) Run(run func(current string, base string, other string)) *MockCmd_MergeFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockCmd_MergeFile_Call) Return(_a0 bool, _a1 error) *MockCmd_MergeFile_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockCmd_MergeFile_Call) RunAndReturn(run func(string, string, string) (bool, error)) *MockCmd_MergeFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // PushTags provides a mock function with given fields: verbose, tag
 func (_m *MockCmd) PushTags(verbose bool, tag string) error {
 	ret := _m.Called(verbose, tag)
@@ -377,6 +603,49 @@ func (_c *MockCmd_Run_Call) RunAndReturn(run func(bool, ...string) (string, erro
 	return _c
 }
 
+// ShallowClone provides a mock function with given fields: clone, workdir
+func (_m *MockCmd) ShallowClone(clone git.GitClone, workdir string) error {
+	ret := _m.Called(clone, workdir)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(git.GitClone, string) error); ok {
+		r0 = rf(clone, workdir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockCmd_ShallowClone_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ShallowClone'
+type MockCmd_ShallowClone_Call struct {
+	*mock.Call
+}
+
+// ShallowClone is a helper method to define mock.On call
+//   - clone git.GitClone
+//   - workdir string
+func (_e *MockCmd_Expecter) ShallowClone(clone interface{}, workdir interface{}) *MockCmd_ShallowClone_Call {
+	return &MockCmd_ShallowClone_Call{Call: _e.mock.On("ShallowClone", clone, workdir)}
+}
+
+func (_c *MockCmd_ShallowClone_Call) Run(run func(clone git.GitClone, workdir string)) *MockCmd_ShallowClone_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(git.GitClone), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockCmd_ShallowClone_Call) Return(_a0 error) *MockCmd_ShallowClone_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCmd_ShallowClone_Call) RunAndReturn(run func(git.GitClone, string) error) *MockCmd_ShallowClone_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Tag provides a mock function with given fields: verbose, tag, commit
 func (_m *MockCmd) Tag(verbose bool, tag string, commit string) error {
 	ret := _m.Called(verbose, tag, commit)
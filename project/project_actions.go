@@ -0,0 +1,497 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KaribuLab/kli/git"
+	"gopkg.in/yaml.v3"
+)
+
+// projectAction es una acción portable que kli ejecuta en Go sobre el
+// proyecto generado, después de copiar los archivos y antes de los hooks
+// postcopy. Los campos que usa cada acción dependen de Type:
+//
+//	gitInit     branch, message, remote
+//	rename      from, to (nuevo nombre en el mismo directorio)
+//	move        from, to
+//	delete      path (admite globs)
+//	chmod       path (admite globs), mode
+//	appendFile  path, content
+//	jsonPatch   path, patch
+//	yamlSet     path, key, value
+//	insert      path, anchor, content, position (before o after)
+//
+// Las rutas son relativas al proyecto y no pueden salir de él. Los textos se
+// renderizan con los inputs y when es una condición como la de los hooks.
+type projectAction struct {
+	Type     string               `json:"type"`
+	When     string               `json:"when"`
+	Path     string               `json:"path"`
+	From     string               `json:"from"`
+	To       string               `json:"to"`
+	Mode     string               `json:"mode"`
+	Content  string               `json:"content"`
+	Anchor   string               `json:"anchor"`
+	Position string               `json:"position"`
+	Patch    []jsonPatchOperation `json:"patch"`
+	Key      string               `json:"key"`
+	Value    any                  `json:"value"`
+	Branch   string               `json:"branch"`
+	Message  string               `json:"message"`
+	Remote   string               `json:"remote"`
+}
+
+// actionRunner ejecuta las acciones sobre el proyecto ubicado en dir
+type actionRunner struct {
+	dir      string
+	renderer *renderer
+	gitCmd   git.Cmd
+	stdout   io.Writer
+}
+
+func (a actionRunner) runActions(actions []projectAction) error {
+	for i, action := range actions {
+		if action.When != "" {
+			condition, err := a.renderer.renderString("when", action.When)
+			if err != nil {
+				return fmt.Errorf("action %d (%s): error rendering when: %w", i+1, action.Type, err)
+			}
+			if !isTruthy(condition) {
+				continue
+			}
+		}
+		fmt.Fprintf(a.stdout, "Running action %s\n", action.Type)
+		err := a.runAction(action)
+		if err != nil {
+			return fmt.Errorf("action %d (%s): %w", i+1, action.Type, err)
+		}
+	}
+	return nil
+}
+
+func (a actionRunner) runAction(action projectAction) error {
+	switch action.Type {
+	case "gitInit":
+		return a.gitInit(action)
+	case "rename":
+		return a.rename(action)
+	case "move":
+		return a.move(action)
+	case "delete":
+		return a.delete(action)
+	case "chmod":
+		return a.chmod(action)
+	case "appendFile":
+		return a.appendFile(action)
+	case "jsonPatch":
+		return a.jsonPatch(action)
+	case "yamlSet":
+		return a.yamlSet(action)
+	case "insert":
+		return a.insert(action)
+	}
+	return fmt.Errorf("unknown action type %q", action.Type)
+}
+
+// path renderiza rel y retorna su ruta absoluta dentro del proyecto. Los
+// directorios intermedios se resuelven con contained, así que un enlace
+// simbólico no permite salir del proyecto.
+func (a actionRunner) path(rel string) (string, error) {
+	if strings.TrimSpace(rel) == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	rendered, err := a.renderer.renderString("path", rel)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(a.dir, filepath.FromSlash(rendered))
+	relPath, err := filepath.Rel(a.dir, target)
+	if err != nil || filepath.IsAbs(rendered) || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside of the project", rendered)
+	}
+	return a.contained(target, false)
+}
+
+// file es como path pero también sigue el último segmento si es un enlace,
+// para las acciones que leen o escriben el contenido del archivo
+func (a actionRunner) file(rel string) (string, error) {
+	target, err := a.path(rel)
+	if err != nil {
+		return "", err
+	}
+	return a.contained(target, true)
+}
+
+// contained retorna la ruta real de target, que está en dir, resolviendo los
+// enlaces simbólicos de sus directorios y falla si sale del proyecto. El
+// último segmento solo se sigue con follow, porque mover o eliminar un enlace
+// no afecta a su destino.
+func (a actionRunner) contained(target string, follow bool) (string, error) {
+	rel, err := filepath.Rel(a.dir, target)
+	if err != nil {
+		return "", err
+	}
+	var resolved string
+	if follow {
+		resolved, err = resolveInRoot(a.dir, rel)
+	} else {
+		resolved, err = resolveInRoot(a.dir, filepath.Dir(rel))
+		resolved = filepath.Join(resolved, filepath.Base(rel))
+	}
+	if errors.Is(err, errOutsideRoot) {
+		return "", fmt.Errorf("path %s is outside of the project", filepath.ToSlash(rel))
+	}
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// glob retorna los archivos del proyecto que coinciden con pattern
+func (a actionRunner) glob(pattern string) ([]string, error) {
+	target, err := a.path(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return filepath.Glob(target)
+}
+
+func (a actionRunner) gitInit(action projectAction) error {
+	settings := gitSettings{Init: true, Branch: action.Branch, Message: action.Message, Remote: action.Remote}
+	return initRepository(a.gitCmd, a.dir, settings, a.renderer, a.stdout)
+}
+
+func (a actionRunner) rename(action projectAction) error {
+	from, err := a.path(action.From)
+	if err != nil {
+		return err
+	}
+	name, err := a.renderer.renderString("to", action.To)
+	if err != nil {
+		return err
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("rename target must be a file name: %q", name)
+	}
+	return moveProjectPath(from, filepath.Join(filepath.Dir(from), name))
+}
+
+func (a actionRunner) move(action projectAction) error {
+	from, err := a.path(action.From)
+	if err != nil {
+		return err
+	}
+	to, err := a.path(action.To)
+	if err != nil {
+		return err
+	}
+	return moveProjectPath(from, to)
+}
+
+func moveProjectPath(from, to string) error {
+	if _, err := os.Lstat(from); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(to); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	}
+	err := os.MkdirAll(filepath.Dir(to), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+func (a actionRunner) delete(action projectAction) error {
+	matches, err := a.glob(action.Path)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		match, err = a.contained(match, false)
+		if err != nil {
+			return err
+		}
+		err = os.RemoveAll(match)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a actionRunner) chmod(action projectAction) error {
+	mode, err := strconv.ParseUint(action.Mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid mode %q", action.Mode)
+	}
+	matches, err := a.glob(action.Path)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no files match %s", action.Path)
+	}
+	for _, match := range matches {
+		match, err = a.contained(match, true)
+		if err != nil {
+			return err
+		}
+		err = os.Chmod(match, os.FileMode(mode))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a actionRunner) appendFile(action projectAction) error {
+	target, err := a.file(action.Path)
+	if err != nil {
+		return err
+	}
+	content, err := a.renderer.renderString("content", action.Content)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// renderValue renderiza los textos contenidos en value
+func (a actionRunner) renderValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return a.renderer.renderString("value", v)
+	case map[string]any:
+		rendered := make(map[string]any, len(v))
+		for key, item := range v {
+			renderedItem, err := a.renderValue(item)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = renderedItem
+		}
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(v))
+		for i, item := range v {
+			renderedItem, err := a.renderValue(item)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = renderedItem
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+func (a actionRunner) jsonPatch(action projectAction) error {
+	target, err := a.file(action.Path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	payload, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	document, err := parseOrderedJSON(payload)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", action.Path, err)
+	}
+	for _, operation := range action.Patch {
+		operation.Value, err = a.renderValue(operation.Value)
+		if err != nil {
+			return err
+		}
+		document, err = applyJSONPatch(document, operation)
+		if err != nil {
+			return err
+		}
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", detectJSONIndent(payload))
+	err = encoder.Encode(document)
+	if err != nil {
+		return err
+	}
+	output := buffer.Bytes()
+	if !bytes.HasSuffix(payload, []byte("\n")) {
+		output = bytes.TrimRight(output, "\n")
+	}
+	return os.WriteFile(target, output, info.Mode().Perm())
+}
+
+// yamlSet asigna value a la clave key (separada por puntos, como
+// server.port) conservando el orden y los comentarios del archivo
+func (a actionRunner) yamlSet(action projectAction) error {
+	target, err := a.file(action.Path)
+	if err != nil {
+		return err
+	}
+	if action.Key == "" {
+		return fmt.Errorf("key is required")
+	}
+	mode := os.FileMode(0644)
+	var document yaml.Node
+	payload, err := os.ReadFile(target)
+	if err == nil {
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+		err = yaml.Unmarshal(payload, &document)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", action.Path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if len(document.Content) == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	value, err := a.renderValue(action.Value)
+	if err != nil {
+		return err
+	}
+	var valueNode yaml.Node
+	err = valueNode.Encode(value)
+	if err != nil {
+		return err
+	}
+	node := document.Content[0]
+	keys := strings.Split(action.Key, ".")
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a mapping", strings.Join(keys[:i], "."))
+		}
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				child = node.Content[j+1]
+				break
+			}
+		}
+		last := i == len(keys)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		if last {
+			valueNode.HeadComment = child.HeadComment
+			valueNode.LineComment = child.LineComment
+			valueNode.FootComment = child.FootComment
+			*child = valueNode
+		}
+		node = child
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&document)
+	if err != nil {
+		return err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, buffer.Bytes(), mode)
+}
+
+// insert agrega content antes o después de la primera línea que contiene
+// anchor, con la misma indentación. Si el archivo ya contiene el texto no se
+// vuelve a insertar, para que aplicar dos veces un generador no lo duplique.
+func (a actionRunner) insert(action projectAction) error {
+	target, err := a.file(action.Path)
+	if err != nil {
+		return err
+	}
+	if action.Anchor == "" {
+		return fmt.Errorf("anchor is required")
+	}
+	if action.Position != "" && action.Position != "before" && action.Position != "after" {
+		return fmt.Errorf("invalid position %q, expected before or after", action.Position)
+	}
+	anchor, err := a.renderer.renderString("anchor", action.Anchor)
+	if err != nil {
+		return err
+	}
+	content, err := a.renderer.renderString("content", action.Content)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	payload, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(string(payload), "\n")
+	index := -1
+	for i, line := range lines {
+		if strings.Contains(line, anchor) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("anchor %q not found in %s", anchor, action.Path)
+	}
+	snippet := indentSnippet(content, lines[index][:len(lines[index])-len(strings.TrimLeft(lines[index], " \t"))])
+	if strings.Contains(string(payload), snippet) {
+		return nil
+	}
+	if action.Position == "after" {
+		if !strings.HasSuffix(lines[index], "\n") {
+			lines[index] += "\n"
+		}
+		index++
+	}
+	lines = append(lines[:index], append([]string{snippet}, lines[index:]...)...)
+	return os.WriteFile(target, []byte(strings.Join(lines, "")), info.Mode().Perm())
+}
+
+// indentSnippet antepone indentation a cada línea no vacía de content y
+// asegura que termine en un salto de línea
+func indentSnippet(content string, indentation string) string {
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indentation + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,237 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KaribuLab/kli/config"
+	"github.com/KaribuLab/kli/git"
+)
+
+const cacheMetaFileName = "meta.json"
+const cacheTemplateDirName = "template"
+
+// templateCache guarda las plantillas descargadas en el directorio de caché
+// del usuario. Cada entrada se identifica por el hash del origen, el ref y el
+// subdirectorio.
+type templateCache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	Key       string    `json:"key"`
+	Source    string    `json:"source"`
+	Ref       string    `json:"ref"`
+	Subdir    string    `json:"subdir"`
+	Commit    string    `json:"commit,omitempty"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// loadedTemplate es una plantilla lista para renderizar. Commit solo se
+// conoce para plantillas obtenidas desde Git.
+type loadedTemplate struct {
+	Path   string
+	Commit string
+}
+
+// fetchOptions controla el uso de la caché al obtener una plantilla
+type fetchOptions struct {
+	Offline bool
+	Refresh bool
+}
+
+func openTemplateCache() (*templateCache, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &templateCache{dir: dir}, nil
+}
+
+func cacheKey(location string, ref string, subdir string) string {
+	hash := sha256.Sum256([]byte(location + "\n" + ref + "\n" + subdir))
+	return hex.EncodeToString(hash[:16])
+}
+
+func (c *templateCache) templatePath(key string) string {
+	return filepath.Join(c.dir, key, cacheTemplateDirName)
+}
+
+func (c *templateCache) Get(key string) (string, bool) {
+	templatePath := c.templatePath(key)
+	if info, err := os.Stat(templatePath); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return templatePath, true
+}
+
+// Put copia templatePath a la caché. La entrada se escribe en un directorio
+// temporal y se reemplaza solo cuando la copia termina.
+func (c *templateCache) Put(entry cacheEntry, templatePath string) error {
+	err := os.MkdirAll(c.dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	tempPath, err := os.MkdirTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempPath)
+	err = copyAll(templatePath, filepath.Join(tempPath, cacheTemplateDirName))
+	if err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(tempPath, cacheMetaFileName), payload, 0644)
+	if err != nil {
+		return err
+	}
+	entryPath := filepath.Join(c.dir, entry.Key)
+	err = os.RemoveAll(entryPath)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, entryPath)
+}
+
+// Commit retorna el commit guardado para la entrada, si existe
+func (c *templateCache) Commit(key string) string {
+	payload, err := os.ReadFile(filepath.Join(c.dir, key, cacheMetaFileName))
+	if err != nil {
+		return ""
+	}
+	var entry cacheEntry
+	if json.Unmarshal(payload, &entry) != nil {
+		return ""
+	}
+	return entry.Commit
+}
+
+func (c *templateCache) List() ([]cacheEntry, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []cacheEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), ".") {
+			continue
+		}
+		payload, err := os.ReadFile(filepath.Join(c.dir, dir.Name(), cacheMetaFileName))
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if json.Unmarshal(payload, &entry) != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FetchedAt.After(entries[j].FetchedAt)
+	})
+	return entries, nil
+}
+
+func (c *templateCache) Remove(key string) error {
+	return os.RemoveAll(filepath.Join(c.dir, key))
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// isCacheable indica si el origen requiere red. Los directorios y archivos
+// locales se leen siempre directamente.
+func isCacheable(source templateSource) bool {
+	switch source.(type) {
+	case *gitSource, *httpSource:
+		return true
+	}
+	return false
+}
+
+// loadTemplate obtiene la plantilla en dst y retorna su directorio raíz. Los
+// orígenes remotos se guardan en la caché; una plantilla con ref fijado o un
+// archivo HTTP se reutiliza desde la caché salvo que se pida Refresh, y con
+// Offline nunca se accede a la red. Falla si algún enlace simbólico de la
+// plantilla apunta fuera de ella.
+func loadTemplate(gitCmd git.Cmd, ref templateRef, branch string, options fetchOptions, dst string, stderr io.Writer) (loadedTemplate, error) {
+	loaded, err := fetchOrCached(gitCmd, ref, branch, options, dst, stderr)
+	if err != nil {
+		return loaded, err
+	}
+	err = checkSymlinks(loaded.Path)
+	if err != nil {
+		return loaded, fmt.Errorf("template %s: %w", ref, err)
+	}
+	return loaded, nil
+}
+
+func fetchOrCached(gitCmd git.Cmd, ref templateRef, branch string, options fetchOptions, dst string, stderr io.Writer) (loadedTemplate, error) {
+	source, err := resolveSource(gitCmd, ref, branch)
+	if err != nil {
+		return loadedTemplate{}, err
+	}
+	if !isCacheable(source) {
+		templatePath, err := fetchTemplate(source, ref.Subdir, dst)
+		return loadedTemplate{Path: templatePath}, err
+	}
+	entry := cacheEntry{Source: ref.Location, Subdir: ref.Subdir}
+	if gitSource, ok := source.(*gitSource); ok {
+		entry.Ref = gitSource.ref
+	}
+	entry.Key = cacheKey(entry.Source, entry.Ref, entry.Subdir)
+	cache, err := openTemplateCache()
+	if err != nil {
+		return loadedTemplate{}, err
+	}
+	pinned := ref.Ref != "" || entry.Ref == ""
+	cachedPath, cached := cache.Get(entry.Key)
+	if options.Offline && !cached {
+		return loadedTemplate{}, fmt.Errorf("template %s is not cached, run without --offline to download it", ref)
+	}
+	if cached && (options.Offline || (pinned && !options.Refresh)) {
+		fmt.Println("Using cached template", ref)
+		err = copyAll(cachedPath, dst)
+		if err != nil {
+			return loadedTemplate{}, err
+		}
+		return loadedTemplate{Path: dst, Commit: cache.Commit(entry.Key)}, nil
+	}
+	templatePath, err := fetchTemplate(source, ref.Subdir, dst)
+	if err != nil {
+		return loadedTemplate{}, err
+	}
+	if gitSource, ok := source.(*gitSource); ok {
+		entry.Commit = gitSource.commit
+	}
+	entry.FetchedAt = time.Now()
+	err = cache.Put(entry, templatePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: error caching template %s: %s\n", ref, err)
+	}
+	return loadedTemplate{Path: templatePath, Commit: entry.Commit}, nil
+}
@@ -1,9 +1,42 @@
 package project
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const projectConfigFileName = ".kliproject.json"
+
+// projectConfigFileNames son los nombres aceptados para la configuración de
+// la plantilla. Todos tienen el mismo esquema y solo puede existir uno.
+var projectConfigFileNames = []string{projectConfigFileName, ".kliproject.yaml", ".kliproject.yml", ".kliproject.toml"}
+
+// projectConfig es el contenido de .kliproject.json. Su JSON Schema está en
+// schema/kliproject.schema.json y debe mantenerse sincronizado con este tipo.
 type projectConfig struct {
-	Prompts   []input    `json:"prompts"`
-	Posthooks []postHook `json:"posthooks"`
-	Templates []template `json:"templates"`
+	Schema      string          `json:"$schema"`
+	Description string          `json:"description"`
+	Prompts     []input         `json:"prompts"`
+	Posthooks   []projectHook   `json:"posthooks"`
+	Hooks       lifecycleHooks  `json:"hooks"`
+	Actions     []projectAction `json:"actions"`
+	Git         gitSettings     `json:"git"`
+	Templates   []template      `json:"templates"`
+	Initialisms []string        `json:"initialisms"`
+	Render      []string        `json:"render"`
+	Delimiters  *delimiters     `json:"delimiters"`
+	Overrides   []fileOverride  `json:"overrides"`
+	Partials    string          `json:"partials"`
+	Layers      []templateLayer `json:"layers"`
 }
 
 type template struct {
@@ -13,8 +46,25 @@ type template struct {
 }
 
 type file struct {
-	Source      string `json:"source"`
-	Destination string `json:"destination"`
+	Source      string      `json:"source"`
+	Destination string      `json:"destination"`
+	Delimiters  *delimiters `json:"delimiters"`
+	Raw         bool        `json:"raw"`
+}
+
+// delimiters reemplaza los delimitadores {{ }} de text/template, útil cuando
+// los archivos de la plantilla ya los usan (Helm, GitHub Actions, etc.)
+type delimiters struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// fileOverride aplica delimitadores propios o el modo raw a los archivos que
+// coinciden con Glob
+type fileOverride struct {
+	Glob       string      `json:"glob"`
+	Delimiters *delimiters `json:"delimiters,omitempty"`
+	Raw        bool        `json:"raw,omitempty"`
 }
 
 type input struct {
@@ -23,11 +73,326 @@ type input struct {
 	Type        string `json:"type"`
 }
 
-type postHook struct {
-	Name    string `json:"name"`
-	Command string `json:"command"`
+// projectHook es un comando que se ejecuta durante la generación. command se
+// ejecuta con un shell (sh -c o cmd /C por defecto, o el definido en shell) y
+// args se ejecuta directamente sin shell. command, args, env y workdir se
+// renderizan con los inputs, y when es una condición sobre ellos. Si output
+// tiene un valor, la salida estándar del hook se guarda en ese input.
+type projectHook struct {
+	Name            string            `json:"name"`
+	Command         string            `json:"command"`
+	Args            []string          `json:"args"`
+	Shell           []string          `json:"shell"`
+	Env             map[string]string `json:"env"`
+	Workdir         string            `json:"workdir"`
+	When            string            `json:"when"`
+	ContinueOnError bool              `json:"continueOnError"`
+	Timeout         string            `json:"timeout"`
+	Output          string            `json:"output"`
+}
+
+// lifecycleHooks agrupa los hooks de cada etapa de la generación. preprompt y
+// prerender se ejecutan en el directorio temporal de la plantilla antes de
+// preguntar los inputs y antes de renderizar, postrender en el mismo
+// directorio ya renderizado y postcopy en el proyecto después de copiar los
+// archivos. posthooks es un alias de postcopy.
+type lifecycleHooks struct {
+	Preprompt  []projectHook `json:"preprompt"`
+	Prerender  []projectHook `json:"prerender"`
+	Postrender []projectHook `json:"postrender"`
+	Postcopy   []projectHook `json:"postcopy"`
 }
 
 type projectPrompt struct {
 	Inputs map[string]interface{}
 }
+
+// postcopyHooks retorna los hooks de posthooks seguidos de los de
+// hooks.postcopy
+func (c projectConfig) postcopyHooks() []projectHook {
+	return append(append([]projectHook{}, c.Posthooks...), c.Hooks.Postcopy...)
+}
+
+// outputHooks retorna los hooks de todas las etapas que calculan un input
+func (c projectConfig) outputHooks() []projectHook {
+	var hooks []projectHook
+	for _, stage := range [][]projectHook{c.Hooks.Preprompt, c.Hooks.Prerender, c.Hooks.Postrender, c.postcopyHooks()} {
+		for _, hook := range stage {
+			if hook.Output != "" {
+				hooks = append(hooks, hook)
+			}
+		}
+	}
+	return hooks
+}
+
+// hasHooks indica si la configuración declara hooks en alguna etapa
+func (c projectConfig) hasHooks() bool {
+	h := c.Hooks
+	return len(c.Posthooks)+len(h.Preprompt)+len(h.Prerender)+len(h.Postrender)+len(h.Postcopy) > 0
+}
+
+// findProjectConfig retorna el nombre del archivo de configuración de la
+// plantilla, o un error si no hay ninguno o hay más de uno
+func findProjectConfig(templatePath string) (string, error) {
+	var found []string
+	for _, name := range projectConfigFileNames {
+		if _, err := os.Stat(path.Join(templatePath, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%s not found in template: %w", strings.Join(projectConfigFileNames, ", "), os.ErrNotExist)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("the template has more than one config file: %s", strings.Join(found, ", "))
+}
+
+func loadProjectConfig(templatePath string) (projectConfig, error) {
+	var projectConfig projectConfig
+	name, err := findProjectConfig(templatePath)
+	if err != nil {
+		return projectConfig, err
+	}
+	payload, err := os.ReadFile(path.Join(templatePath, name))
+	if err != nil {
+		return projectConfig, err
+	}
+	switch path.Ext(name) {
+	case ".yaml", ".yml":
+		payload, err = yamlToJSON(payload)
+	case ".toml":
+		payload, err = tomlToJSON(payload)
+	}
+	if err == nil {
+		err = decodeProjectConfig(payload, &projectConfig)
+		// Solo se indica la posición en .kliproject.json porque en los demás
+		// formatos payload es el JSON convertido y no el archivo original. Los
+		// errores de sintaxis de YAML y TOML ya incluyen su línea.
+		if err != nil && name == projectConfigFileName {
+			err = locateConfigError(payload, err)
+		}
+	}
+	if err != nil {
+		return projectConfig, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return projectConfig, nil
+}
+
+// yamlToJSON convierte la configuración en YAML a JSON para decodificarla con
+// las mismas reglas que .kliproject.json
+func yamlToJSON(payload []byte) ([]byte, error) {
+	var value any
+	err := yaml.Unmarshal(payload, &value)
+	if err != nil {
+		return nil, err
+	}
+	value, err = normalizeYAML(value)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = map[string]any{}
+	}
+	return json.Marshal(value)
+}
+
+// normalizeYAML convierte los mapas con claves no string que produce yaml.v3
+// en map[string]any
+func normalizeYAML(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			normalized, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = normalized
+		}
+		return v, nil
+	case map[any]any:
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			normalized, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(key)] = normalized
+		}
+		return result, nil
+	case []any:
+		for i, item := range v {
+			normalized, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = normalized
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// tomlToJSON convierte la configuración en TOML a JSON. Los saltos de línea
+// CRLF se normalizan para que los strings multilínea no incluyan \r.
+func tomlToJSON(payload []byte) ([]byte, error) {
+	value := make(map[string]any)
+	_, err := toml.Decode(string(bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))), &value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// decodeProjectConfig decodifica payload rechazando los campos desconocidos
+func decodeProjectConfig(payload []byte, projectConfig *projectConfig) error {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(projectConfig)
+	if err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected content after the configuration")
+	}
+	return nil
+}
+
+// locateConfigError agrega a un error de decodeProjectConfig la línea y
+// columna de payload donde ocurrió. encoding/json no indica la posición de los
+// campos desconocidos, así que se busca la clave en payload.
+func locateConfigError(payload []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	offset := int64(-1)
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		if keyOffset, ok := unknownKeyOffset(payload, reflect.TypeOf(projectConfig{})); ok {
+			offset = keyOffset
+		}
+	}
+	if offset < 0 {
+		return err
+	}
+	line, column := lineColumn(payload, offset)
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+// unknownKeyOffset retorna el offset de la primera clave de payload que no
+// corresponde a un campo de typ ni de los structs que contiene, que es la que
+// reporta DisallowUnknownFields. Las claves de los mapas siempre son válidas.
+func unknownKeyOffset(payload []byte, typ reflect.Type) (int64, bool) {
+	type frame struct {
+		typ    reflect.Type
+		object bool
+		key    bool
+		next   reflect.Type
+	}
+	var stack []frame
+	// value marca que el objeto actual espera otra clave
+	value := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].key = true
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, false
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '}' || delim == ']' {
+				stack = stack[:len(stack)-1]
+				value()
+				continue
+			}
+			current := typ
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				current = parent.next
+				if !parent.object {
+					current = elemType(parent.typ)
+				}
+			}
+			stack = append(stack, frame{typ: derefType(current), object: delim == '{', key: delim == '{'})
+			continue
+		}
+		if len(stack) == 0 || !stack[len(stack)-1].object || !stack[len(stack)-1].key {
+			value()
+			continue
+		}
+		top := &stack[len(stack)-1]
+		top.key = false
+		top.next = nil
+		if top.typ == nil {
+			continue
+		}
+		switch top.typ.Kind() {
+		case reflect.Map:
+			top.next = top.typ.Elem()
+		case reflect.Struct:
+			field, ok := jsonField(top.typ, token.(string))
+			if !ok {
+				end := decoder.InputOffset()
+				quoted, _ := json.Marshal(token)
+				if start := bytes.LastIndex(payload[:end], quoted); start >= 0 {
+					return int64(start), true
+				}
+				return end, true
+			}
+			top.next = field.Type
+		}
+	}
+}
+
+func derefType(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+func elemType(typ reflect.Type) reflect.Type {
+	if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+		return typ.Elem()
+	}
+	return nil
+}
+
+// jsonField busca el campo de typ que encoding/json asigna a key
+func jsonField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// lineColumn convierte un offset de payload en línea y columna, ambas desde 1
+func lineColumn(payload []byte, offset int64) (int, int) {
+	if offset > int64(len(payload)) {
+		offset = int64(len(payload))
+	}
+	before := payload[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
@@ -2,7 +2,8 @@ package project
 
 import (
 	"bufio"
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,80 +11,11 @@ import (
 	"path/filepath"
 	"strings"
 	texttemplate "text/template"
-	"unicode"
 
 	"github.com/KaribuLab/kli/git"
 	"github.com/spf13/cobra"
 )
 
-var templateFunctions map[string]any = texttemplate.FuncMap{
-	"toLowerCase":  strings.ToLower,
-	"toUpperCase":  strings.ToUpper,
-	"toPascalCase": ToPascalCase,
-	"toCamelCase":  ToCamelCase,
-}
-
-func ToPascalCase(s string) string {
-	// Utilizamos un Builder para construir la cadena de manera eficiente.
-	var builder strings.Builder
-
-	// Función para determinar si un carácter es un separador.
-	isSeparator := func(r rune) bool {
-		return r == ' ' || r == '-' || r == '_' || r == '.' || r == '/' || r == '\\'
-	}
-
-	// Separa la cadena en palabras basadas en los separadores definidos.
-	words := strings.FieldsFunc(s, isSeparator)
-
-	for _, word := range words {
-		if len(word) == 0 {
-			continue
-		}
-		// Convierte el primer carácter a mayúscula y el resto a minúscula.
-		// Esto maneja correctamente las letras Unicode.
-		runes := []rune(word)
-		runes[0] = unicode.ToUpper(runes[0])
-		for i := 1; i < len(runes); i++ {
-			runes[i] = unicode.ToLower(runes[i])
-		}
-		builder.WriteString(string(runes))
-	}
-
-	return builder.String()
-}
-
-func ToCamelCase(s string) string {
-	// Utilizamos un Builder para construir la cadena de manera eficiente.
-	var builder strings.Builder
-
-	// Función para determinar si un carácter es un separador.
-	isSeparator := func(r rune) bool {
-		return r == ' ' || r == '-' || r == '_' || r == '.' || r == '/' || r == '\\'
-	}
-
-	// Separa la cadena en palabras basadas en los separadores definidos.
-	words := strings.FieldsFunc(s, isSeparator)
-
-	for i, word := range words {
-		if len(word) == 0 {
-			continue
-		}
-		// Convertir la primera palabra a minúsculas y las siguientes con la primera letra en mayúscula.
-		runes := []rune(word)
-		if i == 0 {
-			runes[0] = unicode.ToLower(runes[0])
-		} else {
-			runes[0] = unicode.ToUpper(runes[0])
-		}
-		for j := 1; j < len(runes); j++ {
-			runes[j] = unicode.ToLower(runes[j])
-		}
-		builder.WriteString(string(runes))
-	}
-
-	return builder.String()
-}
-
 func copyAll(src, dst string) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -101,84 +33,471 @@ func copyAll(src, dst string) error {
 	})
 }
 
+// copyFile copia src en dst conservando sus permisos y su fecha de
+// modificación. Los enlaces simbólicos se recrean en lugar de copiar el
+// archivo al que apuntan.
 func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return copySymlink(src, dst)
+	}
 	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
-	out, err := os.Create(dst)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 	_, err = io.Copy(out, in)
-	return err
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return preserveMetadata(dst, info)
 }
 
-func buildDestinationPath(workdir string, destination string, propmt projectPrompt) (string, error) {
-	tmpl, err := texttemplate.New("base").Parse(destination)
+func copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		err = os.Remove(dst)
+		if err != nil {
+			return err
+		}
 	}
-	var builder strings.Builder
-	err = tmpl.Execute(&builder, propmt)
+	return os.Symlink(target, dst)
+}
+
+// preserveMetadata aplica a path los permisos y la fecha de modificación de
+// info. Los permisos se fijan explícitamente porque la umask puede haber
+// eliminado el bit de ejecución al crear el archivo.
+func preserveMetadata(path string, info os.FileInfo) error {
+	err := os.Chmod(path, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+func buildDestinationPath(workdir string, destination string, r *renderer) (string, error) {
+	rendered, err := r.renderString("base", destination)
 	if err != nil {
 		return "", err
 	}
-	return path.Join(workdir, builder.String()), nil
+	return path.Join(workdir, rendered), nil
 }
 
-func writeTemplates(template []template, workdir string, prompt projectPrompt) error {
+// writeTemplates genera los archivos de templates y retorna sus destinos,
+// relativos a workdir y con separadores /
+func writeTemplates(template []template, workdir string, r *renderer) (map[string]bool, error) {
+	written := make(map[string]bool)
 	for _, t := range template {
-		var templateFileList []string
+		// Los archivos se agrupan por delimitadores; los de un mismo grupo se
+		// parsean juntos y pueden referenciarse entre sí.
+		groups := make(map[delimiters][]string)
 		for _, f := range t.Files {
 			sourceFilePath := path.Join(workdir, f.Source)
-			templateFileList = append(templateFileList, sourceFilePath)
+			if f.Raw || r.isRaw(f.Source) || isBinaryFile(sourceFilePath) {
+				continue
+			}
+			d := r.delimitersFor(f.Source, f.Delimiters)
+			groups[d] = append(groups[d], sourceFilePath)
 		}
-		tmpl := texttemplate.New("base").Funcs(templateFunctions)
-		tmpl, err := tmpl.ParseFiles(templateFileList...)
-		if err != nil {
-			return err
+		sets := make(map[delimiters]*texttemplate.Template)
+		for d, templateFileList := range groups {
+			tmpl, err := r.newTemplate("base", d)
+			if err != nil {
+				return nil, err
+			}
+			tmpl, err = tmpl.ParseFiles(templateFileList...)
+			if err != nil {
+				return nil, err
+			}
+			sets[d] = tmpl
 		}
 		for _, f := range t.Files {
-			destinationFilePath, err := buildDestinationPath(workdir, f.Destination, prompt)
+			destinationFilePath, err := buildDestinationPath(workdir, f.Destination, r)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if rel, err := filepath.Rel(workdir, destinationFilePath); err == nil {
+				written[filepath.ToSlash(rel)] = true
 			}
 			sourceFilePath := filepath.Base(path.Join(workdir, f.Source))
 			err = os.MkdirAll(path.Dir(destinationFilePath), os.ModePerm)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			// Los archivos raw y binarios se copian sin pasar por text/template
+			if f.Raw || r.isRaw(f.Source) || isBinaryFile(path.Join(workdir, f.Source)) {
+				err = copyFile(path.Join(workdir, f.Source), destinationFilePath)
+				if err != nil {
+					return nil, err
+				}
+				continue
 			}
-			file, err := os.Create(destinationFilePath)
+			tmpl := sets[r.delimitersFor(f.Source, f.Delimiters)]
+			sourceInfo, err := os.Stat(path.Join(workdir, f.Source))
 			if err != nil {
-				return err
+				return nil, err
 			}
-			err = tmpl.ExecuteTemplate(file, sourceFilePath, prompt)
+			file, err := os.OpenFile(destinationFilePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, sourceInfo.Mode().Perm())
 			if err != nil {
-				return err
+				return nil, err
 			}
+			err = tmpl.ExecuteTemplate(file, sourceFilePath, r.prompt)
 			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			err = preserveMetadata(destinationFilePath, sourceInfo)
+			if err != nil {
+				return nil, err
+			}
 		}
 		if t.Delete {
 			os.RemoveAll(path.Join(workdir, t.RootDir))
 		}
 	}
+	return written, nil
+}
+
+// fetchTemplate descarga la plantilla en dst y retorna el directorio raíz de
+// la plantilla, que es subdir dentro de dst.
+func fetchTemplate(source templateSource, subdir string, dst string) (string, error) {
+	err := source.Fetch(dst)
+	if err != nil {
+		return "", err
+	}
+	templatePath := path.Join(dst, subdir)
+	if info, err := os.Stat(templatePath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("template directory %s not found in %s", subdir, source)
+	}
+	os.RemoveAll(path.Join(templatePath, ".git"))
+	return templatePath, nil
+}
+
+// askPrompts solicita al usuario los inputs que aún no tienen valor
+func askPrompts(reader *bufio.Reader, prompts []input, inputs map[string]any) error {
+	for _, prompt := range prompts {
+		if _, ok := inputs[prompt.Name]; ok {
+			continue
+		}
+		fmt.Println(prompt.Description)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		inputs[prompt.Name] = strings.TrimSpace(input)
+	}
 	return nil
 }
 
+// renderProject aplica las plantillas y los nombres dinámicos sobre
+// templatePath, dejando el directorio listo para copiarse al proyecto.
+func renderProject(templatePath string, projectConfig projectConfig, prompt projectPrompt) (*renderer, error) {
+	r, err := newRenderer(templatePath, projectConfig, prompt)
+	if err != nil {
+		return nil, err
+	}
+	// Los parciales ya están cargados en el renderer y, al igual que .kli,
+	// no forman parte del proyecto generado
+	for _, dir := range []string{projectConfig.Partials, templateMetaDir} {
+		if dir == "" {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(templatePath, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Los patrones de .kliignore se comparan con las rutas de la plantilla,
+	// antes de generar los archivos de templates y de renombrar
+	rules, err := loadIgnoreRules(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	err = removeIgnored(templatePath, rules)
+	if err != nil {
+		return nil, err
+	}
+	written, err := writeTemplates(projectConfig.Templates, templatePath, r)
+	if err != nil {
+		return nil, err
+	}
+	// Los archivos generados por templates ya están renderizados
+	err = renderGlobs(templatePath, projectConfig.Render, written, r)
+	if err != nil {
+		return nil, err
+	}
+	err = renderPaths(templatePath, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range append([]string{ignoreFileName}, projectConfigFileNames...) {
+		err = os.Remove(path.Join(templatePath, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// isTemplateConfigFile indica si rel es un archivo de configuración de la
+// plantilla que nunca se copia al proyecto.
+func isTemplateConfigFile(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if rel == ignoreFileName {
+		return true
+	}
+	for _, name := range projectConfigFileNames {
+		if rel == name {
+			return true
+		}
+	}
+	return false
+}
+
+// generation es una aplicación de una plantilla sobre projectPath. Con
+// component se agrega a un proyecto existente (`kli generate`), por lo que no
+// se escribe el archivo de bloqueo ni se inicializa un repositorio Git.
+type generation struct {
+	source      string
+	workdir     string
+	projectPath string
+	component   bool
+}
+
+func (g generation) run(cmd *cobra.Command, gitCmd git.Cmd) error {
+	conflict, err := cmd.Flags().GetString("conflict")
+	if err != nil {
+		return err
+	}
+	policy, err := parseConflictPolicy(conflict)
+	if err != nil {
+		return err
+	}
+	branch, err := cmd.Flags().GetString("branch")
+	if err != nil {
+		return err
+	}
+	templateRef, branch, err := resolveTemplateRef(gitCmd, g.source, branch, cmd.Flags().Changed("branch"), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	tempPath, err := os.MkdirTemp("", "kli_*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempPath)
+	offline, err := cmd.Flags().GetBool("offline")
+	if err != nil {
+		return err
+	}
+	refresh, err := cmd.Flags().GetBool("refresh")
+	if err != nil {
+		return err
+	}
+	options := fetchOptions{Offline: offline, Refresh: refresh}
+	loaded, err := loadTemplate(gitCmd, templateRef, branch, options, path.Join(tempPath, "source"), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	projectConfig, err := loadProjectConfig(loaded.Path)
+	if err != nil {
+		return err
+	}
+	loader := &layerLoader{gitCmd: gitCmd, options: options, tempPath: tempPath, stderr: cmd.ErrOrStderr()}
+	layers, err := loader.load(templateRef, branch, projectConfig)
+	if err != nil {
+		return err
+	}
+	projectConfig.Prompts = layerPrompts(projectConfig.Prompts, layers)
+	noHooks, err := cmd.Flags().GetBool("no-hooks")
+	if err != nil {
+		return err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+	showDiff, err := cmd.Flags().GetBool("diff")
+	if err != nil {
+		return err
+	}
+	// Una vista previa no ejecuta comandos de la plantilla, así que los
+	// inputs que calculan los hooks quedan sin valor
+	if dryRun || showDiff {
+		noHooks = true
+		for _, hook := range projectConfig.outputHooks() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: the preview does not run hooks, input %s computed by hook '%s' is missing and renders as <no value>\n", hook.Output, hook.Name)
+		}
+	}
+	reader := bufio.NewReader(cmd.InOrStdin())
+	runHooks, err := confirmHooks(projectConfig, templateRef, hookOptions{NoHooks: noHooks, Yes: yes}, reader, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	if !runHooks {
+		projectConfig.Hooks = lifecycleHooks{}
+		projectConfig.Posthooks = nil
+	}
+	inputs := make(map[string]any)
+	projectPrompt := projectPrompt{
+		Inputs: inputs,
+	}
+	// Los hooks previos al renderizado se ejecutan en el directorio
+	// temporal de la plantilla
+	stageRenderer, err := newRenderer(loaded.Path, projectConfig, projectPrompt)
+	if err != nil {
+		return err
+	}
+	stageHooks := hookRunner{
+		dir:      loaded.Path,
+		renderer: stageRenderer,
+		stdout:   cmd.OutOrStdout(),
+		stderr:   cmd.ErrOrStderr(),
+	}
+	stageHooks.stage = "preprompt"
+	err = stageHooks.runHooks(projectConfig.Hooks.Preprompt)
+	if err != nil {
+		return err
+	}
+	err = askPrompts(reader, projectConfig.Prompts, inputs)
+	if err != nil {
+		return err
+	}
+	stageHooks.stage = "prerender"
+	err = stageHooks.runHooks(projectConfig.Hooks.Prerender)
+	if err != nil {
+		return err
+	}
+	r, err := renderProject(loaded.Path, projectConfig, projectPrompt)
+	if err != nil {
+		return err
+	}
+	err = renderLayers(loaded.Path, layers, projectPrompt)
+	if err != nil {
+		return err
+	}
+	stageHooks.stage = "postrender"
+	stageHooks.renderer = r
+	err = stageHooks.runHooks(projectConfig.Hooks.Postrender)
+	if err != nil {
+		return err
+	}
+	preserveTimes, err := cmd.Flags().GetBool("preserve-times")
+	if err != nil {
+		return err
+	}
+	plan, err := planCopy(loaded.Path, g.projectPath)
+	if err != nil {
+		return err
+	}
+	if dryRun || showDiff {
+		err = resolvePlan(plan, policy, nil, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		printPlan(cmd.OutOrStdout(), g.workdir, plan)
+		if showDiff {
+			return printDiffs(cmd.OutOrStdout(), plan)
+		}
+		return nil
+	}
+	err = resolvePlan(plan, policy, reader, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	err = applyPlan(plan, preserveTimes, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	if !g.component {
+		err = writeProjectLock(g.projectPath, newProjectLock(g.source, templateRef, branch, loaded.Commit, layers, inputs))
+		if err != nil {
+			return err
+		}
+	}
+	actions := actionRunner{
+		dir:      g.projectPath,
+		renderer: r,
+		gitCmd:   gitCmd,
+		stdout:   cmd.OutOrStdout(),
+	}
+	// Las acciones de cada capa usan sus propios delimitadores
+	for _, layer := range layers {
+		actions.renderer = layer.renderer
+		err = actions.runActions(layer.config.Actions)
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.ref, err)
+		}
+	}
+	actions.renderer = r
+	err = actions.runActions(projectConfig.Actions)
+	if err != nil {
+		return err
+	}
+	hooks := hookRunner{
+		stage:    "postcopy",
+		dir:      g.projectPath,
+		renderer: r,
+		stdout:   cmd.OutOrStdout(),
+		stderr:   cmd.ErrOrStderr(),
+	}
+	err = hooks.runHooks(projectConfig.postcopyHooks())
+	if err != nil {
+		return err
+	}
+	if g.component {
+		return nil
+	}
+	// El repositorio se inicializa al final para que el commit inicial
+	// incluya los cambios de las acciones y los hooks
+	settings, err := gitSettingsFromFlags(cmd, projectConfig.Git)
+	if err != nil {
+		return err
+	}
+	if !settings.Init {
+		return nil
+	}
+	return initRepository(gitCmd, g.projectPath, settings, r, cmd.OutOrStdout())
+}
+
+// addGenerationFlags agrega los flags comunes de `kli project` y `kli generate`
+func addGenerationFlags(cmd *cobra.Command, workdirUsage string) {
+	cmd.Flags().Bool("dry-run", false, "Show the files that would be created without writing them or running hooks")
+	cmd.Flags().Bool("diff", false, "Show a unified diff of the existing files that would be overwritten, without writing them or running hooks")
+	cmd.Flags().String("conflict", string(conflictFail), "What to do with existing files: fail, skip, overwrite, prompt or backup")
+	cmd.Flags().Bool("preserve-times", false, "Keep the modification times of the template files")
+	cmd.Flags().Bool("no-hooks", false, "Do not run the template hooks")
+	cmd.Flags().BoolP("yes", "y", false, "Run the template hooks without asking for confirmation")
+	cmd.Flags().StringP("branch", "b", "main", "Branch to clone when the template does not pin a ref with @<ref>")
+	cmd.Flags().StringP("workdir", "w", ".", workdirUsage)
+	cmd.Flags().Bool("offline", false, "Use the cached template without accessing the network")
+	cmd.Flags().Bool("refresh", false, "Download the template again even if it is cached")
+}
+
 func NewProjectCommand(gitCmd git.Cmd) *cobra.Command {
 	projectCommand := &cobra.Command{
-		Use:   "project",
+		Use:   "project <template>",
 		Short: "Create a new project",
+		Long:  "Create a new project from a template located in a Git repository, a local directory, or a .tar.gz/.zip archive (local or HTTP)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repository := args[0]
-			branch, err := cmd.Flags().GetString("branch")
-			if err != nil {
-				return err
-			}
 			workdir, err := cmd.Flags().GetString("workdir")
 			if err != nil {
 				return err
@@ -187,62 +506,54 @@ func NewProjectCommand(gitCmd git.Cmd) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			tempPath, err := os.MkdirTemp("", "kli_*")
-			if err != nil {
-				return err
-			}
-			defer os.RemoveAll(tempPath)
-			tempWorkingDirPath := path.Join(tempPath, workdir)
-			err = gitCmd.Clone(repository, branch, tempWorkingDirPath)
-			if err != nil {
-				return err
-			}
-			os.RemoveAll(path.Join(tempWorkingDirPath, ".git"))
-			payload, err := os.ReadFile(path.Join(tempWorkingDirPath, ".kliproject.json"))
-			if err != nil {
-				return err
-			}
-			var projectConfig projectConfig
-			err = json.Unmarshal(payload, &projectConfig)
-			if err != nil {
-				return err
-			}
-			inputs := make(map[string]any)
-			reader := bufio.NewReader(os.Stdin)
-			for _, prompt := range projectConfig.Prompts {
-				fmt.Println(prompt.Description)
-				input, err := reader.ReadString('\n')
-				if err != nil {
-					return err
-				}
-				inputs[prompt.Name] = strings.TrimSpace(input)
-			}
-			projectPrompt := projectPrompt{
-				Inputs: inputs,
-			}
-			err = writeTemplates(projectConfig.Templates, tempWorkingDirPath, projectPrompt)
-			if err != nil {
-				return err
-			}
-			err = copyAll(tempWorkingDirPath, path.Join(cwd, workdir))
-			if err != nil {
-				return err
-			}
-			err = os.Remove(path.Join(cwd, workdir, ".kliproject.json"))
-			if err != nil {
-				return err
-			}
-			for _, hook := range projectConfig.Posthooks {
-				fmt.Printf("Running posthook '%s': %s\n", hook.Name, hook.Command)
-				err = runHook(path.Join(cwd, workdir), hook.Command)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
+			g := generation{source: args[0], workdir: workdir, projectPath: path.Join(cwd, workdir)}
+			return g.run(cmd, gitCmd)
 		},
 	}
-	projectCommand.Flags().StringP("branch", "b", "main", "Branch to clone")
-	projectCommand.Flags().StringP("workdir", "w", ".", "Working directory")
+	addGenerationFlags(projectCommand, "Working directory")
+	projectCommand.Flags().Bool("git", false, "Initialize a Git repository with an initial commit (overrides the template setting)")
+	projectCommand.Flags().String("git-branch", "", "Default branch of the new Git repository (default \"main\")")
+	projectCommand.Flags().String("git-remote", "", "URL of the origin remote of the new Git repository")
+	projectCommand.AddCommand(newProjectUpdateCommand(gitCmd))
 	return projectCommand
 }
+
+// gitSettingsFromFlags combina el bloque git de la plantilla con los flags
+// --git, --git-branch y --git-remote, que tienen prioridad
+func gitSettingsFromFlags(cmd *cobra.Command, settings gitSettings) (gitSettings, error) {
+	if cmd.Flags().Changed("git") {
+		enabled, err := cmd.Flags().GetBool("git")
+		if err != nil {
+			return settings, err
+		}
+		settings.Init = enabled
+	}
+	if cmd.Flags().Changed("git-branch") {
+		branch, err := cmd.Flags().GetString("git-branch")
+		if err != nil {
+			return settings, err
+		}
+		settings.Branch = branch
+	}
+	if cmd.Flags().Changed("git-remote") {
+		remote, err := cmd.Flags().GetString("git-remote")
+		if err != nil {
+			return settings, err
+		}
+		settings.Remote = remote
+	}
+	return settings, nil
+}
+
+// isBinaryFile indica si el archivo contiene un byte nulo en sus primeros
+// 8000 bytes, el mismo criterio que usa Git.
+func isBinaryFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	buffer := make([]byte, 8000)
+	n, _ := io.ReadFull(file, buffer)
+	return bytes.IndexByte(buffer[:n], 0) >= 0
+}
@@ -0,0 +1,230 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KaribuLab/kli/git"
+)
+
+// templateMetaDir es el directorio de la plantilla con archivos para sus
+// autores (fixtures y goldens). Nunca se copia al proyecto generado.
+const templateMetaDir = ".kli"
+
+// templateFixture son las respuestas con las que se prueba una plantilla. Su
+// salida esperada está en .kli/golden/<Name>.
+type templateFixture struct {
+	Name   string
+	Inputs map[string]any
+}
+
+// loadFixtures lee los archivos .json, .yaml y .yml de .kli/fixtures. Cada
+// archivo es un objeto con el valor de cada input.
+func loadFixtures(templatePath string) ([]templateFixture, error) {
+	dir := filepath.Join(templatePath, templateMetaDir, "fixtures")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no fixtures found in %s", filepath.Join(templateMetaDir, "fixtures"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []templateFixture
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		payload, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if ext != ".json" {
+			payload, err = yamlToJSON(payload)
+			if err != nil {
+				return nil, fmt.Errorf("error reading fixture %s: %w", entry.Name(), err)
+			}
+		}
+		fixture := templateFixture{Name: strings.TrimSuffix(entry.Name(), ext)}
+		err = json.Unmarshal(payload, &fixture.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixture %s: %w", entry.Name(), err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", filepath.Join(templateMetaDir, "fixtures"))
+	}
+	sort.Slice(fixtures, func(i, j int) bool {
+		return fixtures[i].Name < fixtures[j].Name
+	})
+	return fixtures, nil
+}
+
+// renderFixture renderiza la plantilla con las respuestas del fixture en
+// dst, sin ejecutar hooks ni inicializar un repositorio. Las acciones sí se
+// aplican porque forman parte del resultado.
+func renderFixture(loader *layerLoader, templatePath string, fixture templateFixture, dst string) error {
+	err := copyAll(templatePath, dst)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(filepath.Join(dst, ".git"))
+	if err != nil {
+		return err
+	}
+	projectConfig, err := loadProjectConfig(dst)
+	if err != nil {
+		return err
+	}
+	layers, err := loader.load(templateRef{Location: templatePath}, "main", projectConfig)
+	if err != nil {
+		return err
+	}
+	for _, prompt := range layerPrompts(projectConfig.Prompts, layers) {
+		if _, ok := fixture.Inputs[prompt.Name]; !ok {
+			return fmt.Errorf("fixture does not answer prompt %s", prompt.Name)
+		}
+	}
+	prompt := projectPrompt{Inputs: copyAnswers(fixture.Inputs)}
+	r, err := renderProject(dst, projectConfig, prompt)
+	if err != nil {
+		return err
+	}
+	err = renderLayers(dst, layers, prompt)
+	if err != nil {
+		return err
+	}
+	runner := actionRunner{dir: dst, stdout: io.Discard}
+	for _, layer := range layers {
+		runner.renderer = layer.renderer
+		err = runner.runActions(withoutGitInit(layer.config.Actions))
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.ref, err)
+		}
+	}
+	runner.renderer = r
+	return runner.runActions(withoutGitInit(projectConfig.Actions))
+}
+
+func withoutGitInit(actions []projectAction) []projectAction {
+	var filtered []projectAction
+	for _, action := range actions {
+		if action.Type != "gitInit" {
+			filtered = append(filtered, action)
+		}
+	}
+	return filtered
+}
+
+// compareTree escribe en out las diferencias entre el golden y output y
+// retorna si son iguales
+func compareTree(golden, output string, out io.Writer) (bool, error) {
+	if _, err := os.Stat(golden); err != nil {
+		fmt.Fprintf(out, "    golden directory %s not found, run with --update to create it\n", golden)
+		return false, nil
+	}
+	goldenFiles, err := listFiles(golden)
+	if err != nil {
+		return false, err
+	}
+	outputFiles, err := listFiles(output)
+	if err != nil {
+		return false, err
+	}
+	var paths []string
+	for rel := range goldenFiles {
+		paths = append(paths, rel)
+	}
+	for rel := range outputFiles {
+		if !goldenFiles[rel] {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	equal := true
+	for _, rel := range paths {
+		goldenFile := filepath.Join(golden, filepath.FromSlash(rel))
+		outputFile := filepath.Join(output, filepath.FromSlash(rel))
+		switch {
+		case !outputFiles[rel]:
+			fmt.Fprintf(out, "    missing file %s\n", rel)
+			equal = false
+		case !goldenFiles[rel]:
+			fmt.Fprintf(out, "    unexpected file %s\n", rel)
+			equal = false
+		case !sameContent(goldenFile, outputFile):
+			equal = false
+			if isBinaryFile(goldenFile) || isBinaryFile(outputFile) {
+				fmt.Fprintf(out, "    Binary files golden/%s and output/%s differ\n", rel, rel)
+				continue
+			}
+			err = writeUnifiedDiff(out, goldenFile, outputFile, "golden/"+rel, "output/"+rel)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	return equal, nil
+}
+
+// runTemplateTests renderiza cada fixture de la plantilla y lo compara con su
+// golden. Con update reemplaza los goldens por la salida actual.
+func runTemplateTests(gitCmd git.Cmd, templatePath string, update bool, out io.Writer) error {
+	fixtures, err := loadFixtures(templatePath)
+	if err != nil {
+		return err
+	}
+	tempPath, err := os.MkdirTemp("", "kli_*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempPath)
+	loader := &layerLoader{gitCmd: gitCmd, tempPath: tempPath, stderr: out}
+	failed := 0
+	for _, fixture := range fixtures {
+		output := filepath.Join(tempPath, fixture.Name)
+		golden := filepath.Join(templatePath, templateMetaDir, "golden", fixture.Name)
+		err := renderFixture(loader, templatePath, fixture, output)
+		if err != nil {
+			fmt.Fprintf(out, "FAIL %s\n    %v\n", fixture.Name, err)
+			failed++
+			continue
+		}
+		if update {
+			err = os.RemoveAll(golden)
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(golden), os.ModePerm)
+			}
+			if err == nil {
+				err = copyAll(output, golden)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "updated %s\n", fixture.Name)
+			continue
+		}
+		var diff strings.Builder
+		equal, err := compareTree(golden, output, &diff)
+		if err != nil {
+			return err
+		}
+		if equal {
+			fmt.Fprintf(out, "ok   %s\n", fixture.Name)
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "FAIL %s\n%s", fixture.Name, diff.String())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixtures failed", failed, len(fixtures))
+	}
+	return nil
+}
@@ -0,0 +1,692 @@
+package project
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode"
+)
+
+var templateFunctions map[string]any = texttemplate.FuncMap{
+	// Conversión de mayúsculas y minúsculas
+	"toLowerCase":    strings.ToLower,
+	"toUpperCase":    strings.ToUpper,
+	"toPascalCase":   ToPascalCase,
+	"toCamelCase":    ToCamelCase,
+	"toSnakeCase":    ToSnakeCase,
+	"toKebabCase":    ToKebabCase,
+	"toConstantCase": ToConstantCase,
+	"toTitleCase":    ToTitleCase,
+	"pluralize":      Pluralize,
+	"singularize":    Singularize,
+	// Alias compatibles con Sprig
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      ToTitleCase,
+	"camelcase":  ToPascalCase,
+	"snakecase":  ToSnakeCase,
+	"kebabcase":  ToKebabCase,
+	"plural":     Pluralize,
+	"singular":   Singularize,
+	"trim":       strings.TrimSpace,
+	"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+	"substr":     substr,
+	"trunc":      trunc,
+	"quote":      func(s any) string { return fmt.Sprintf("%q", toString(s)) },
+	"squote":     func(s any) string { return "'" + toString(s) + "'" },
+	"shellQuote": shellQuote,
+	"cat":        cat,
+	"split":      split,
+	"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+	"join":       join,
+	"toString":   toString,
+	"toJson":     toJSON,
+	// Valores por defecto y condiciones
+	"default":  defaultValue,
+	"empty":    isEmpty,
+	"coalesce": coalesce,
+	"ternary":  ternary,
+	// Listas y diccionarios
+	"list":   list,
+	"append": appendList,
+	"first":  first,
+	"last":   last,
+	"has":    has,
+	"dict":   dict,
+	"get":    get,
+	"set":    set,
+	"hasKey": hasKey,
+	"keys":   keys,
+	// Fechas
+	"now":  time.Now,
+	"date": date,
+	// Identificadores, codificación y hashes
+	"uuidv4":    uuidv4,
+	"uuid":      uuidv4,
+	"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec":    b64dec,
+	"sha1sum":   func(s string) string { h := sha1.Sum([]byte(s)); return hex.EncodeToString(h[:]) },
+	"sha256sum": func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) },
+	// Indentación
+	"indent":  indent,
+	"nindent": func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+}
+
+// initialisms son las siglas que se mantienen en mayúsculas al convertir a
+// PascalCase, camelCase o formato título
+type initialisms map[string]bool
+
+// defaultInitialisms son las siglas que reconocen siempre las funciones de
+// conversión. Cada plantilla puede agregar otras con el campo "initialisms" de
+// .kliproject.json sin modificar este mapa.
+var defaultInitialisms = initialisms{
+	"ACL":   true,
+	"API":   true,
+	"ASCII": true,
+	"AWS":   true,
+	"CPU":   true,
+	"CSS":   true,
+	"DNS":   true,
+	"EOF":   true,
+	"GUID":  true,
+	"HTML":  true,
+	"HTTP":  true,
+	"HTTPS": true,
+	"ID":    true,
+	"IP":    true,
+	"JSON":  true,
+	"JWT":   true,
+	"RPC":   true,
+	"SDK":   true,
+	"SQL":   true,
+	"SSH":   true,
+	"TCP":   true,
+	"TLS":   true,
+	"TTL":   true,
+	"UDP":   true,
+	"UI":    true,
+	"UID":   true,
+	"URI":   true,
+	"URL":   true,
+	"UUID":  true,
+	"XML":   true,
+	"YAML":  true,
+}
+
+// withInitialisms retorna las siglas por defecto más extra
+func withInitialisms(extra ...string) initialisms {
+	result := make(initialisms, len(defaultInitialisms)+len(extra))
+	for initialism := range defaultInitialisms {
+		result[initialism] = true
+	}
+	for _, initialism := range extra {
+		initialism = strings.TrimSpace(initialism)
+		if initialism != "" {
+			result[strings.ToUpper(initialism)] = true
+		}
+	}
+	return result
+}
+
+// functions retorna las funciones de plantilla con las conversiones de
+// formato ligadas a estas siglas
+func (in initialisms) functions() texttemplate.FuncMap {
+	functions := make(texttemplate.FuncMap, len(templateFunctions))
+	for name, function := range templateFunctions {
+		functions[name] = function
+	}
+	functions["toPascalCase"] = in.pascalCase
+	functions["toCamelCase"] = in.camelCase
+	functions["toTitleCase"] = in.titleCase
+	functions["title"] = in.titleCase
+	functions["camelcase"] = in.pascalCase
+	return functions
+}
+
+func isSeparator(r rune) bool {
+	return r == ' ' || r == '-' || r == '_' || r == '.' || r == '/' || r == '\\'
+}
+
+// splitWords separa la cadena en palabras. Además de los separadores, corta en
+// los cambios de minúscula a mayúscula ("myApi" -> "my", "Api"), al final de
+// una secuencia de mayúsculas seguida de una minúscula ("HTTPServer" ->
+// "HTTP", "Server") y entre letras y dígitos ("v2Api" -> "v", "2", "Api").
+func splitWords(s string) []string {
+	var words []string
+	for _, field := range strings.FieldsFunc(s, isSeparator) {
+		runes := []rune(field)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, curr := runes[i-1], runes[i]
+			boundary := false
+			switch {
+			case unicode.IsDigit(prev) != unicode.IsDigit(curr):
+				boundary = true
+			case unicode.IsLower(prev) && unicode.IsUpper(curr):
+				boundary = true
+			case unicode.IsUpper(prev) && unicode.IsUpper(curr) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+				boundary = true
+			}
+			if boundary {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func (in initialisms) capitalize(word string) string {
+	if in[strings.ToUpper(word)] {
+		return strings.ToUpper(word)
+	}
+	// Convierte el primer carácter a mayúscula y el resto a minúscula.
+	// Esto maneja correctamente las letras Unicode.
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func joinWords(s string, separator string, transform func(int, string) string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = transform(i, word)
+	}
+	return strings.Join(words, separator)
+}
+
+// ToPascalCase convierte "http_server" o "httpServer" en "HTTPServer".
+func ToPascalCase(s string) string {
+	return defaultInitialisms.pascalCase(s)
+}
+
+func (in initialisms) pascalCase(s string) string {
+	return joinWords(s, "", func(_ int, word string) string {
+		return in.capitalize(word)
+	})
+}
+
+// ToCamelCase convierte "HTTP server" o "http-server" en "httpServer".
+func ToCamelCase(s string) string {
+	return defaultInitialisms.camelCase(s)
+}
+
+func (in initialisms) camelCase(s string) string {
+	return joinWords(s, "", func(i int, word string) string {
+		if i == 0 {
+			return strings.ToLower(word)
+		}
+		return in.capitalize(word)
+	})
+}
+
+// ToSnakeCase convierte "HTTPServer" en "http_server".
+func ToSnakeCase(s string) string {
+	return joinWords(s, "_", func(_ int, word string) string {
+		return strings.ToLower(word)
+	})
+}
+
+// ToKebabCase convierte "HTTPServer" en "http-server".
+func ToKebabCase(s string) string {
+	return joinWords(s, "-", func(_ int, word string) string {
+		return strings.ToLower(word)
+	})
+}
+
+// ToConstantCase convierte "HTTPServer" en "HTTP_SERVER".
+func ToConstantCase(s string) string {
+	return joinWords(s, "_", func(_ int, word string) string {
+		return strings.ToUpper(word)
+	})
+}
+
+// ToTitleCase convierte "http_server" en "HTTP Server".
+func ToTitleCase(s string) string {
+	return defaultInitialisms.titleCase(s)
+}
+
+func (in initialisms) titleCase(s string) string {
+	return joinWords(s, " ", func(_ int, word string) string {
+		return in.capitalize(word)
+	})
+}
+
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"person": "people",
+	"man":    "men",
+	"woman":  "women",
+	"mouse":  "mice",
+	"goose":  "geese",
+	"foot":   "feet",
+	"tooth":  "teeth",
+}
+
+// fPlurals son los sustantivos terminados en f o fe cuyo plural termina en
+// ves. El resto de las palabras con esas terminaciones son regulares (safe,
+// roof, chief, archive, drive).
+var fPlurals = map[string]string{
+	"calf":  "calves",
+	"elf":   "elves",
+	"half":  "halves",
+	"knife": "knives",
+	"leaf":  "leaves",
+	"life":  "lives",
+	"loaf":  "loaves",
+	"self":  "selves",
+	"shelf": "shelves",
+	"thief": "thieves",
+	"wife":  "wives",
+	"wolf":  "wolves",
+}
+
+// sPlurals son sustantivos terminados en s cuyo plural agrega es. Los demás
+// plurales terminados en ses (cases, databases, houses) solo pierden la s.
+var sPlurals = map[string]bool{
+	"alias":  true,
+	"atlas":  true,
+	"bias":   true,
+	"bonus":  true,
+	"bus":    true,
+	"campus": true,
+	"canvas": true,
+	"census": true,
+	"focus":  true,
+	"gas":    true,
+	"lens":   true,
+	"minus":  true,
+	"plus":   true,
+	"status": true,
+	"virus":  true,
+}
+
+var uncountableWords = map[string]bool{
+	"data":        true,
+	"information": true,
+	"equipment":   true,
+	"metadata":    true,
+	"news":        true,
+	"series":      true,
+	"species":     true,
+}
+
+// matchCase aplica a replacement el formato de mayúsculas de original.
+func matchCase(original, replacement string) string {
+	switch {
+	case original == strings.ToUpper(original) && original != strings.ToLower(original):
+		return strings.ToUpper(replacement)
+	case unicode.IsUpper([]rune(original)[0]):
+		runes := []rune(replacement)
+		runes[0] = unicode.ToUpper(runes[0])
+		return string(runes)
+	}
+	return replacement
+}
+
+// keepStemCase conserva las mayúsculas de original en la parte que comparte
+// con inflected, que está en minúsculas.
+func keepStemCase(original, inflected string) string {
+	if original == strings.ToUpper(original) {
+		return strings.ToUpper(inflected)
+	}
+	lower := strings.ToLower(original)
+	if len(lower) != len(original) {
+		return inflected
+	}
+	n := 0
+	for n < len(lower) && n < len(inflected) && lower[n] == inflected[n] {
+		n++
+	}
+	return original[:n] + inflected[n:]
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
+
+// Pluralize convierte un sustantivo en inglés a su forma plural.
+func Pluralize(s string) string {
+	lower := strings.ToLower(s)
+	if lower == "" || uncountableWords[lower] {
+		return s
+	}
+	if plural, ok := irregularPlurals[lower]; ok {
+		return matchCase(s, plural)
+	}
+	if plural, ok := fPlurals[lower]; ok {
+		return keepStemCase(s, plural)
+	}
+	var plural string
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !isVowel(lower[len(lower)-2]):
+		plural = lower[:len(lower)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		plural = lower + "es"
+	default:
+		plural = lower + "s"
+	}
+	return keepStemCase(s, plural)
+}
+
+// Singularize convierte un sustantivo en inglés a su forma singular.
+func Singularize(s string) string {
+	lower := strings.ToLower(s)
+	if lower == "" || uncountableWords[lower] {
+		return s
+	}
+	for singular, plural := range irregularPlurals {
+		if lower == plural {
+			return matchCase(s, singular)
+		}
+	}
+	for singular, plural := range fPlurals {
+		if lower == plural {
+			return keepStemCase(s, singular)
+		}
+	}
+	var singular string
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		singular = lower[:len(lower)-3] + "y"
+	case strings.HasSuffix(lower, "ses") && sPlurals[lower[:len(lower)-2]]:
+		singular = lower[:len(lower)-2]
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		singular = lower[:len(lower)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		singular = lower
+	case strings.HasSuffix(lower, "s"):
+		singular = lower[:len(lower)-1]
+	default:
+		singular = lower
+	}
+	return keepStemCase(s, singular)
+}
+
+func toString(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case fmt.Stringer:
+		return value.String()
+	case error:
+		return value.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+// shellQuote escapa v como un único argumento de sh. Las palabras sin
+// caracteres especiales se retornan tal cual y el resto se encierra entre
+// comillas simples, por lo que un input no puede agregar comandos a un hook.
+func shellQuote(v any) string {
+	s := toString(v)
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_@%+=:,./-", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func toJSON(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+func substr(start, end int, s string) string {
+	runes := []rune(s)
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 || end > len(runes) {
+		end = len(runes)
+	}
+	if start > end {
+		return ""
+	}
+	return string(runes[start:end])
+}
+
+func trunc(length int, s string) string {
+	runes := []rune(s)
+	if length < 0 {
+		if -length >= len(runes) {
+			return s
+		}
+		return string(runes[len(runes)+length:])
+	}
+	if length >= len(runes) {
+		return s
+	}
+	return string(runes[:length])
+}
+
+func cat(values ...any) string {
+	var parts []string
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		parts = append(parts, toString(v))
+	}
+	return strings.Join(parts, " ")
+}
+
+// split replica el comportamiento de Sprig: retorna un diccionario con las
+// claves _0, _1, ... para que las partes sean accesibles desde la plantilla.
+func split(sep, s string) map[string]string {
+	result := make(map[string]string)
+	for i, part := range strings.Split(s, sep) {
+		result[fmt.Sprintf("_%d", i)] = part
+	}
+	return result
+}
+
+func join(sep string, v any) string {
+	var parts []string
+	for _, item := range toList(v) {
+		parts = append(parts, toString(item))
+	}
+	return strings.Join(parts, sep)
+}
+
+func toList(v any) []any {
+	if v == nil {
+		return nil
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]any, value.Len())
+		for i := range items {
+			items[i] = value.Index(i).Interface()
+		}
+		return items
+	}
+	return []any{v}
+}
+
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return value.IsNil()
+	}
+	return false
+}
+
+func defaultValue(def any, given ...any) any {
+	if len(given) == 0 || isEmpty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+func coalesce(values ...any) any {
+	for _, v := range values {
+		if !isEmpty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ternary(whenTrue, whenFalse any, condition bool) any {
+	if condition {
+		return whenTrue
+	}
+	return whenFalse
+}
+
+func list(items ...any) []any {
+	return items
+}
+
+func appendList(v any, item any) []any {
+	return append(toList(v), item)
+}
+
+func first(v any) any {
+	items := toList(v)
+	if len(items) == 0 {
+		return nil
+	}
+	return items[0]
+}
+
+func last(v any) any {
+	items := toList(v)
+	if len(items) == 0 {
+		return nil
+	}
+	return items[len(items)-1]
+}
+
+func has(needle any, haystack any) bool {
+	for _, item := range toList(haystack) {
+		if reflect.DeepEqual(item, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict requires an even number of arguments")
+	}
+	result := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		result[toString(pairs[i])] = pairs[i+1]
+	}
+	return result, nil
+}
+
+func get(d map[string]any, key string) any {
+	return d[key]
+}
+
+func set(d map[string]any, key string, value any) map[string]any {
+	d[key] = value
+	return d
+}
+
+func hasKey(d map[string]any, key string) bool {
+	_, ok := d[key]
+	return ok
+}
+
+func keys(d map[string]any) []string {
+	result := make([]string, 0, len(d))
+	for key := range d {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// date formatea una fecha usando el layout de Go, igual que Sprig.
+func date(layout string, v any) (string, error) {
+	switch value := v.(type) {
+	case time.Time:
+		return value.Format(layout), nil
+	case *time.Time:
+		return value.Format(layout), nil
+	case int:
+		return time.Unix(int64(value), 0).Format(layout), nil
+	case int64:
+		return time.Unix(value, 0).Format(layout), nil
+	}
+	return "", fmt.Errorf("date: unsupported value %v", v)
+}
+
+func uuidv4() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func b64dec(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+func indent(spaces int, s string) string {
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(s, "\n", "\n"+padding)
+}
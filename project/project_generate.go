@@ -0,0 +1,84 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/KaribuLab/kli/git"
+	"github.com/spf13/cobra"
+)
+
+// generatorsDir es el directorio de un proyecto con sus generadores locales.
+// `kli generate <nombre>` usa .kligenerators/<nombre> antes que el registro.
+const generatorsDir = ".kligenerators"
+
+// localGenerator retorna el generador del proyecto llamado name, si existe
+func localGenerator(projectPath string, name string) (string, bool) {
+	if !templateNameRegex.MatchString(name) {
+		return "", false
+	}
+	generatorPath := filepath.Join(projectPath, generatorsDir, name)
+	info, err := os.Stat(generatorPath)
+	return generatorPath, err == nil && info.IsDir()
+}
+
+// findProjectRoot retorna el primer directorio desde dir hacia arriba que
+// contiene el lock de kli, generadores locales o un repositorio Git. Si no hay
+// ninguno retorna dir.
+func findProjectRoot(dir string) string {
+	for current := dir; ; {
+		for _, marker := range []string{projectLockFileName, generatorsDir, ".git"} {
+			if _, err := os.Stat(filepath.Join(current, marker)); err == nil {
+				return current
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+func NewGenerateCommand(gitCmd git.Cmd) *cobra.Command {
+	generateCommand := &cobra.Command{
+		Use:     "generate <template>",
+		Aliases: []string{"g"},
+		Short:   "Add a component to an existing project",
+		Long:    "Render a template (a handler, a migration, a function) into an existing project. Its paths are relative to the project directory and its insert actions add snippets to existing files at marked anchors. Templates in " + generatorsDir + "/<name> of the project are used before the registry",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workdir, err := cmd.Flags().GetString("workdir")
+			if err != nil {
+				return err
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			projectPath := path.Join(cwd, workdir)
+			if info, err := os.Stat(projectPath); err != nil || !info.IsDir() {
+				return fmt.Errorf("project directory %s not found", workdir)
+			}
+			// Desde un subdirectorio las rutas se resuelven en la raíz del
+			// proyecto, salvo que se indique --workdir
+			if !cmd.Flags().Changed("workdir") {
+				projectPath = findProjectRoot(projectPath)
+				workdir, err = filepath.Rel(cwd, projectPath)
+				if err != nil {
+					return err
+				}
+			}
+			source := args[0]
+			if generatorPath, ok := localGenerator(projectPath, source); ok {
+				source = generatorPath
+			}
+			g := generation{source: source, workdir: workdir, projectPath: projectPath, component: true}
+			return g.run(cmd, gitCmd)
+		},
+	}
+	addGenerationFlags(generateCommand, "Project directory (default: the nearest parent directory with "+projectLockFileName+", "+generatorsDir+" or .git)")
+	return generateCommand
+}
@@ -0,0 +1,65 @@
+package project
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/KaribuLab/kli/git"
+)
+
+const defaultCommitMessage = "chore: initial commit"
+
+// gitSettings es el bloque git de .kliproject.json, que inicializa un
+// repositorio en el proyecto generado
+type gitSettings struct {
+	Init    bool   `json:"init"`
+	Branch  string `json:"branch"`
+	Message string `json:"message"`
+	Remote  string `json:"remote"`
+}
+
+// initRepository crea un repositorio en dir con la rama settings.Branch,
+// agrega todos los archivos en un commit inicial y, si se indica, el remote
+// origin. El mensaje y el remote se renderizan con los inputs. Si dir ya es
+// un repositorio no hace nada.
+func initRepository(gitCmd git.Cmd, dir string, settings gitSettings, r *renderer, out io.Writer) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		fmt.Fprintf(out, "Skipping git init: %s is already a Git repository\n", dir)
+		return nil
+	}
+	branch := settings.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	message := defaultCommitMessage
+	if settings.Message != "" {
+		rendered, err := r.renderString("message", settings.Message)
+		if err != nil {
+			return err
+		}
+		message = rendered
+	}
+	fmt.Fprintf(out, "Initializing Git repository on branch %s\n", branch)
+	err := gitCmd.Init(dir, branch)
+	if err != nil {
+		return err
+	}
+	err = gitCmd.AddAll(dir)
+	if err != nil {
+		return err
+	}
+	err = gitCmd.Commit(dir, message)
+	if err != nil {
+		return err
+	}
+	if settings.Remote == "" {
+		return nil
+	}
+	remote, err := r.renderString("remote", settings.Remote)
+	if err != nil {
+		return err
+	}
+	return gitCmd.AddRemote(dir, "origin", remote)
+}
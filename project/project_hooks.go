@@ -0,0 +1,177 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultShell retorna el intérprete con el que se ejecuta command cuando el
+// hook no define uno propio
+func defaultShell() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C"}
+	}
+	return []string{"sh", "-c"}
+}
+
+// isTruthy interpreta el resultado de renderizar una condición when
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "0", "no", "<no value>":
+		return false
+	}
+	return true
+}
+
+// hookRunner ejecuta los hooks de una etapa en dir, renderizando sus
+// comandos con los inputs ingresados
+type hookRunner struct {
+	stage    string
+	dir      string
+	renderer *renderer
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+// runHooks ejecuta hooks en orden. Un hook con continueOnError que falla solo
+// emite una advertencia.
+func (h hookRunner) runHooks(hooks []projectHook) error {
+	for _, hook := range hooks {
+		err := h.runHook(hook)
+		if err != nil {
+			if !hook.ContinueOnError {
+				return err
+			}
+			fmt.Fprintf(h.stderr, "warning: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func (h hookRunner) runHook(hook projectHook) error {
+	if hook.When != "" {
+		condition, err := h.renderer.renderString("when", hook.When)
+		if err != nil {
+			return fmt.Errorf("hook %s: error rendering when: %w", hook.Name, err)
+		}
+		if !isTruthy(condition) {
+			fmt.Fprintf(h.stdout, "Skipping %s hook '%s'\n", h.stage, hook.Name)
+			return nil
+		}
+	}
+	argv, err := h.commandLine(hook)
+	if err != nil {
+		return fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	dir, err := h.workdir(hook)
+	if err != nil {
+		return fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	env, err := h.environment(hook)
+	if err != nil {
+		return fmt.Errorf("hook %s: %w", hook.Name, err)
+	}
+	ctx := context.Background()
+	if hook.Timeout != "" {
+		timeout, err := time.ParseDuration(hook.Timeout)
+		if err != nil {
+			return fmt.Errorf("hook %s: invalid timeout %q: %w", hook.Name, hook.Timeout, err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	fmt.Fprintf(h.stdout, "Running %s hook '%s': %s\n", h.stage, hook.Name, strings.Join(argv, " "))
+	command := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	command.Dir = dir
+	command.Env = env
+	var output bytes.Buffer
+	command.Stdout = h.stdout
+	if hook.Output != "" {
+		command.Stdout = &output
+	}
+	command.Stderr = h.stderr
+	// Si el shell termina por timeout, sus procesos hijos pueden mantener
+	// abierta la salida; WaitDelay evita esperar a que terminen.
+	command.WaitDelay = time.Second
+	err = command.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("hook %s timed out after %s", hook.Name, hook.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("hook %s failed: %w", hook.Name, err)
+	}
+	if hook.Output != "" {
+		h.renderer.prompt.Inputs[hook.Output] = strings.TrimSpace(output.String())
+	}
+	return nil
+}
+
+// commandLine construye el argv del hook. args se ejecuta directamente, sin
+// shell; command se pasa al shell del hook o al shell por defecto.
+func (h hookRunner) commandLine(hook projectHook) ([]string, error) {
+	if len(hook.Args) > 0 && hook.Command != "" {
+		return nil, fmt.Errorf("command and args are mutually exclusive")
+	}
+	if len(hook.Args) > 0 {
+		argv := make([]string, 0, len(hook.Args))
+		for _, arg := range hook.Args {
+			rendered, err := h.renderer.renderString("args", arg)
+			if err != nil {
+				return nil, err
+			}
+			argv = append(argv, rendered)
+		}
+		return argv, nil
+	}
+	if strings.TrimSpace(hook.Command) == "" {
+		return nil, fmt.Errorf("command or args is required")
+	}
+	command, err := h.renderer.renderString("command", hook.Command)
+	if err != nil {
+		return nil, err
+	}
+	shell := hook.Shell
+	if len(shell) == 0 {
+		shell = defaultShell()
+	}
+	return append(append([]string{}, shell...), command), nil
+}
+
+// workdir retorna el directorio del hook, que debe estar dentro de dir
+func (h hookRunner) workdir(hook projectHook) (string, error) {
+	if hook.Workdir == "" {
+		return h.dir, nil
+	}
+	rendered, err := h.renderer.renderString("workdir", hook.Workdir)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(h.dir, filepath.FromSlash(rendered))
+	rel, err := filepath.Rel(h.dir, dir)
+	if err != nil || filepath.IsAbs(rendered) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("workdir %s is outside of %s", rendered, h.dir)
+	}
+	return dir, nil
+}
+
+func (h hookRunner) environment(hook projectHook) ([]string, error) {
+	env := os.Environ()
+	for name, value := range hook.Env {
+		rendered, err := h.renderer.renderString("env", value)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, name+"="+rendered)
+	}
+	return env, nil
+}
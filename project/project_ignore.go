@@ -0,0 +1,206 @@
+package project
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const ignoreFileName = ".kliignore"
+
+// pathPattern es un patrón con la sintaxis de .gitignore
+type pathPattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// compilePattern convierte un patrón de .gitignore en una expresión regular
+// que se evalúa sobre rutas relativas separadas por "/". Un patrón sin "/"
+// (salvo al final) se compara con el nombre en cualquier nivel.
+func compilePattern(pattern string) (pathPattern, error) {
+	var p pathPattern
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	var builder strings.Builder
+	builder.WriteString("^")
+	if !anchored {
+		builder.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			builder.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case c == '*':
+			builder.WriteString("[^/]*")
+		case c == '?':
+			builder.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			builder.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	regex, err := regexp.Compile(builder.String())
+	if err != nil {
+		return p, err
+	}
+	p.regex = regex
+	return p, nil
+}
+
+func (p pathPattern) Match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return p.regex.MatchString(rel)
+}
+
+// ignoreRules es la lista de patrones de .kliignore. Como en .gitignore, la
+// última regla que coincide decide y "!" vuelve a incluir una ruta.
+type ignoreRules struct {
+	patterns []pathPattern
+}
+
+func parseIgnoreRules(lines []string) (*ignoreRules, error) {
+	rules := &ignoreRules{}
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		p, err := compilePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules, nil
+}
+
+func loadIgnoreRules(templatePath string) (*ignoreRules, error) {
+	file, err := os.Open(filepath.Join(templatePath, ignoreFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &ignoreRules{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return parseIgnoreRules(lines)
+}
+
+func (r *ignoreRules) Ignored(rel string, isDir bool) bool {
+	rel = path.Clean(filepath.ToSlash(rel))
+	ignored := false
+	for _, p := range r.patterns {
+		if p.Match(rel, isDir) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// removeIgnored elimina de templatePath los archivos y directorios excluidos
+// por las reglas. Un directorio ignorado se elimina completo.
+func removeIgnored(templatePath string, rules *ignoreRules) error {
+	if len(rules.patterns) == 0 {
+		return nil
+	}
+	var ignored []string
+	err := filepath.Walk(templatePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == templatePath {
+			return nil
+		}
+		rel, err := filepath.Rel(templatePath, p)
+		if err != nil {
+			return err
+		}
+		if rules.Ignored(rel, info.IsDir()) {
+			ignored = append(ignored, p)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range ignored {
+		err = os.RemoveAll(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesAny indica si rel coincide con alguno de los globs, que usan la misma
+// sintaxis que .kliignore.
+func matchesAny(globs []pathPattern, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, glob := range globs {
+		if glob.Match(rel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileGlobs(globs []string) ([]pathPattern, error) {
+	var patterns []pathPattern
+	for _, glob := range globs {
+		p, err := compilePattern(glob)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
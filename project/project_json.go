@@ -0,0 +1,277 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonObject es un objeto JSON que conserva el orden de sus claves, para que
+// aplicar un parche a archivos como package.json no reordene su contenido.
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{values: make(map[string]any)}
+}
+
+func (o *jsonObject) get(key string) (any, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+func (o *jsonObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) remove(key string) {
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		encoded, err := marshalJSON(key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encoded)
+		buffer.WriteByte(':')
+		encoded, err = marshalJSON(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encoded)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// marshalJSON codifica value sin escapar <, > y &
+func marshalJSON(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+// parseOrderedJSON decodifica payload usando jsonObject para los objetos y
+// json.Number para los números, de modo que al volver a codificarlo solo
+// cambian los valores modificados.
+func parseOrderedJSON(payload []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	value, err := decodeOrderedJSON(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err == nil {
+		return nil, fmt.Errorf("unexpected content after the JSON value")
+	}
+	return value, nil
+}
+
+func decodeOrderedJSON(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		object := newJSONObject()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.(string), value)
+		}
+		_, err = decoder.Token()
+		return object, err
+	case '[':
+		array := []any{}
+		for decoder.More() {
+			value, err := decodeOrderedJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return nil, fmt.Errorf("unexpected delimiter %s", delim)
+}
+
+// toOrderedJSON convierte los map[string]any de value en jsonObject con las
+// claves ordenadas alfabéticamente
+func toOrderedJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		object := newJSONObject()
+		for _, key := range keys {
+			object.set(key, toOrderedJSON(v[key]))
+		}
+		return object
+	case []any:
+		array := make([]any, len(v))
+		for i, item := range v {
+			array[i] = toOrderedJSON(item)
+		}
+		return array
+	}
+	return value
+}
+
+// detectJSONIndent retorna la indentación de la primera línea indentada de
+// payload, o dos espacios si no hay ninguna
+func detectJSONIndent(payload []byte) string {
+	for _, line := range strings.Split(string(payload), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// jsonPatchOperation es una operación de RFC 6902. Se soportan add, remove y
+// replace.
+type jsonPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// parseJSONPointer divide un JSON Pointer (RFC 6901) en sus segmentos
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// applyJSONPatch aplica operation sobre document y retorna el documento
+// resultante
+func applyJSONPatch(document any, operation jsonPatchOperation) (any, error) {
+	switch operation.Op {
+	case "add", "remove", "replace":
+	default:
+		return nil, fmt.Errorf("unsupported JSON patch operation %q", operation.Op)
+	}
+	tokens, err := parseJSONPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := patchJSONNode(document, tokens, operation.Op, toOrderedJSON(operation.Value))
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", operation.Op, operation.Path, err)
+	}
+	return result, nil
+}
+
+func patchJSONNode(node any, tokens []string, op string, value any) (any, error) {
+	if len(tokens) == 0 {
+		if op == "remove" {
+			return nil, fmt.Errorf("cannot remove the whole document")
+		}
+		return value, nil
+	}
+	token := tokens[0]
+	switch container := node.(type) {
+	case *jsonObject:
+		child, exists := container.get(token)
+		if len(tokens) > 1 {
+			if !exists {
+				return nil, fmt.Errorf("path not found")
+			}
+			child, err := patchJSONNode(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			container.set(token, child)
+			return container, nil
+		}
+		if !exists && op != "add" {
+			return nil, fmt.Errorf("path not found")
+		}
+		if op == "remove" {
+			container.remove(token)
+		} else {
+			container.set(token, value)
+		}
+		return container, nil
+	case []any:
+		if len(tokens) == 1 && op == "add" && token == "-" {
+			return append(container, value), nil
+		}
+		index, err := strconv.Atoi(token)
+		limit := len(container)
+		if len(tokens) == 1 && op == "add" {
+			limit++
+		}
+		if err != nil || index < 0 || index >= limit {
+			return nil, fmt.Errorf("invalid array index %q", token)
+		}
+		if len(tokens) > 1 {
+			child, err := patchJSONNode(container[index], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			container[index] = child
+			return container, nil
+		}
+		switch op {
+		case "add":
+			container = append(container[:index], append([]any{value}, container[index:]...)...)
+		case "remove":
+			container = append(container[:index], container[index+1:]...)
+		default:
+			container[index] = value
+		}
+		return container, nil
+	}
+	return nil, fmt.Errorf("path not found")
+}
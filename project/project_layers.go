@@ -0,0 +1,280 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/KaribuLab/kli/git"
+)
+
+// templateLayer es otra plantilla que se renderiza junto con la que la
+// declara, por ejemplo un complemento de Postgres sobre una plantilla base.
+// Source usa la misma sintaxis que `kli project` y Ref fija un tag, rama o
+// commit. Conflict indica qué hacer cuando la capa genera un archivo que ya
+// generó una capa anterior.
+type templateLayer struct {
+	Source   string `json:"source"`
+	Ref      string `json:"ref"`
+	Conflict string `json:"conflict"`
+}
+
+// layerConflict es la estrategia de una capa para los archivos que ya existen
+type layerConflict string
+
+const (
+	layerOverwrite layerConflict = "overwrite"
+	layerSkip      layerConflict = "skip"
+	layerAppend    layerConflict = "append"
+	layerFail      layerConflict = "fail"
+)
+
+func parseLayerConflict(value string) (layerConflict, error) {
+	switch conflict := layerConflict(value); conflict {
+	case "":
+		return layerOverwrite, nil
+	case layerOverwrite, layerSkip, layerAppend, layerFail:
+		return conflict, nil
+	}
+	return "", fmt.Errorf("unknown conflict strategy %q, expected overwrite, skip, append or fail", value)
+}
+
+// errLayerHooks es el error de una capa que declara hooks
+var errLayerHooks = errors.New("hooks are not supported in layers, declare them in the template that uses the layer")
+
+// isRelativeSource indica si source es relativo a la plantilla que lo declara
+func isRelativeSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// loadedLayer es una capa obtenida y lista para renderizar. renderer se asigna
+// al renderizarla y se usa para sus acciones.
+type loadedLayer struct {
+	ref      templateRef
+	commit   string
+	path     string
+	config   projectConfig
+	conflict layerConflict
+	renderer *renderer
+}
+
+// layerLoader obtiene las capas de una plantilla en tempPath, con las mismas
+// opciones de caché que la plantilla principal. Las capas que coinciden con
+// una de pinned se obtienen en el commit registrado.
+type layerLoader struct {
+	gitCmd   git.Cmd
+	options  fetchOptions
+	tempPath string
+	stderr   io.Writer
+	fetched  int
+	pinned   []lockedLayer
+}
+
+// pin retorna ref con el commit registrado para la capa, si lo hay
+func (l *layerLoader) pin(ref templateRef) templateRef {
+	for _, locked := range l.pinned {
+		if locked.Commit != "" && locked.Source == ref.Location && locked.Subdir == ref.Subdir && locked.Ref == ref.Ref {
+			ref.Ref = locked.Commit
+			return ref
+		}
+	}
+	return ref
+}
+
+// layerRef resuelve la referencia de una capa. Una ruta relativa se resuelve
+// respecto de la plantilla que la declara: en un directorio local es una ruta
+// del sistema de archivos y en un repositorio o archivo es otro subdirectorio
+// del mismo origen, en la misma versión.
+func (l *layerLoader) layerRef(parent templateRef, branch string, layer templateLayer) (templateRef, string, error) {
+	if strings.TrimSpace(layer.Source) == "" {
+		return templateRef{}, branch, fmt.Errorf("source is required")
+	}
+	var ref templateRef
+	switch {
+	case isRelativeSource(layer.Source) && isLocalDirectory(parent):
+		ref = templateRef{Location: filepath.Join(parent.Location, filepath.FromSlash(parent.Subdir), filepath.FromSlash(layer.Source))}
+	case isRelativeSource(layer.Source):
+		subdir := path.Join(parent.Subdir, layer.Source)
+		if !isValidSubdir(subdir) {
+			return ref, branch, fmt.Errorf("source %s is outside of %s", layer.Source, parent.Location)
+		}
+		ref = templateRef{Location: parent.Location, Subdir: subdir, Ref: parent.Ref}
+	default:
+		var err error
+		ref, branch, err = resolveTemplateRef(l.gitCmd, layer.Source, branch, false, l.stderr)
+		if err != nil {
+			return ref, branch, err
+		}
+	}
+	if layer.Ref != "" {
+		ref.Ref = layer.Ref
+	}
+	return ref, branch, nil
+}
+
+// load obtiene recursivamente las capas que declara projectConfig, la
+// configuración de la plantilla parent. Las capas de una capa se ubican antes
+// que ella para que se rendericen primero.
+func (l *layerLoader) load(parent templateRef, branch string, projectConfig projectConfig) ([]*loadedLayer, error) {
+	return l.loadChain(parent, branch, projectConfig, []string{parent.String()})
+}
+
+func (l *layerLoader) loadChain(parent templateRef, branch string, projectConfig projectConfig, chain []string) ([]*loadedLayer, error) {
+	var layers []*loadedLayer
+	for i, layer := range projectConfig.Layers {
+		conflict, err := parseLayerConflict(layer.Conflict)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i+1, layer.Source, err)
+		}
+		ref, layerBranch, err := l.layerRef(parent, branch, layer)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i+1, layer.Source, err)
+		}
+		for _, key := range chain {
+			if key == ref.String() {
+				return nil, fmt.Errorf("layer %s includes itself", ref)
+			}
+		}
+		declared := ref
+		ref = l.pin(ref)
+		l.fetched++
+		loaded, err := loadTemplate(l.gitCmd, ref, layerBranch, l.options, filepath.Join(l.tempPath, fmt.Sprintf("layer-%d", l.fetched)), l.stderr)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", ref, err)
+		}
+		layerConfig, err := loadProjectConfig(loaded.Path)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", ref, err)
+		}
+		// Los hooks de una capa no se ejecutan; se rechazan para que no se
+		// ignoren en silencio
+		if layerConfig.hasHooks() {
+			return nil, fmt.Errorf("layer %s: %w", ref, errLayerHooks)
+		}
+		nested, err := l.loadChain(ref, layerBranch, layerConfig, append(chain[:len(chain):len(chain)], ref.String()))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, nested...)
+		layers = append(layers, &loadedLayer{ref: declared, commit: loaded.Commit, path: loaded.Path, config: layerConfig, conflict: conflict})
+	}
+	return layers, nil
+}
+
+// layerPrompts agrega a prompts los de las capas cuyo nombre no está
+// declarado todavía. La definición de la plantilla principal tiene prioridad.
+func layerPrompts(prompts []input, layers []*loadedLayer) []input {
+	declared := make(map[string]bool)
+	merged := append([]input{}, prompts...)
+	for _, prompt := range prompts {
+		declared[prompt.Name] = true
+	}
+	for _, layer := range layers {
+		for _, prompt := range layer.config.Prompts {
+			if !declared[prompt.Name] {
+				declared[prompt.Name] = true
+				merged = append(merged, prompt)
+			}
+		}
+	}
+	return merged
+}
+
+// renderLayers renderiza cada capa con las mismas respuestas y la combina con
+// la plantilla principal, ya renderizada en stagePath. Las capas se aplican en
+// orden según su estrategia de conflicto y al final los archivos de la
+// plantilla principal, que siempre tienen prioridad.
+func renderLayers(stagePath string, layers []*loadedLayer, prompt projectPrompt) error {
+	if len(layers) == 0 {
+		return nil
+	}
+	combined, err := os.MkdirTemp(filepath.Dir(stagePath), "layers_*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(combined)
+	for _, layer := range layers {
+		layer.renderer, err = renderProject(layer.path, layer.config, prompt)
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.ref, err)
+		}
+		err = mergeLayer(layer.path, combined, layer.conflict)
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.ref, err)
+		}
+	}
+	err = mergeLayer(stagePath, combined, layerOverwrite)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(stagePath)
+	if err != nil {
+		return err
+	}
+	return os.Rename(combined, stagePath)
+}
+
+// mergeLayer mueve los archivos de src a dst resolviendo los que ya existen
+// según conflict
+func mergeLayer(src string, dst string, conflict layerConflict) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		existing, statErr := os.Lstat(target)
+		if info.IsDir() {
+			if statErr == nil && !existing.IsDir() {
+				return fmt.Errorf("directory %s conflicts with a file", filepath.ToSlash(rel))
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if statErr == nil {
+			if existing.IsDir() {
+				return fmt.Errorf("file %s conflicts with a directory", filepath.ToSlash(rel))
+			}
+			switch conflict {
+			case layerSkip:
+				return nil
+			case layerFail:
+				return fmt.Errorf("file %s already exists", filepath.ToSlash(rel))
+			case layerAppend:
+				return appendLayerFile(p, target)
+			}
+		}
+		return os.Rename(p, target)
+	})
+}
+
+// appendLayerFile agrega el contenido de src al final de dst, separándolos
+// con un salto de línea si dst no termina en uno
+func appendLayerFile(src string, dst string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(dst)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(dst, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		content = append([]byte("\n"), content...)
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
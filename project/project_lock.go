@@ -0,0 +1,88 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const projectLockFileName = ".kliproject.lock.json"
+
+// projectLock registra la plantilla y las respuestas con las que se generó un
+// proyecto, para poder aplicar versiones nuevas de la plantilla con
+// `kli project update`.
+type projectLock struct {
+	Template string         `json:"template"`
+	Source   string         `json:"source"`
+	Subdir   string         `json:"subdir,omitempty"`
+	Branch   string         `json:"branch,omitempty"`
+	Ref      string         `json:"ref,omitempty"`
+	Commit   string         `json:"commit,omitempty"`
+	Layers   []lockedLayer  `json:"layers,omitempty"`
+	Answers  map[string]any `json:"answers"`
+}
+
+// lockedLayer registra la versión de una capa con la que se generó el
+// proyecto. Ref es la versión que declara la plantilla y Commit el commit al
+// que se resolvió, que `kli project update` usa para renderizar la base.
+type lockedLayer struct {
+	Source string `json:"source"`
+	Subdir string `json:"subdir,omitempty"`
+	Ref    string `json:"ref,omitempty"`
+	Commit string `json:"commit,omitempty"`
+}
+
+func lockLayers(layers []*loadedLayer) []lockedLayer {
+	var locked []lockedLayer
+	for _, layer := range layers {
+		locked = append(locked, lockedLayer{Source: layer.ref.Location, Subdir: layer.ref.Subdir, Ref: layer.ref.Ref, Commit: layer.commit})
+	}
+	return locked
+}
+
+func newProjectLock(template string, ref templateRef, branch string, commit string, layers []*loadedLayer, answers map[string]any) projectLock {
+	source := ref.Location
+	if _, err := os.Stat(source); err == nil {
+		if absPath, err := filepath.Abs(source); err == nil {
+			source = absPath
+		}
+	}
+	return projectLock{
+		Template: template,
+		Source:   source,
+		Subdir:   ref.Subdir,
+		Branch:   branch,
+		Ref:      ref.Ref,
+		Commit:   commit,
+		Layers:   lockLayers(layers),
+		Answers:  answers,
+	}
+}
+
+func readProjectLock(projectPath string) (projectLock, error) {
+	var lock projectLock
+	payload, err := os.ReadFile(path.Join(projectPath, projectLockFileName))
+	if err != nil {
+		return lock, err
+	}
+	err = json.Unmarshal(payload, &lock)
+	if lock.Answers == nil {
+		lock.Answers = make(map[string]any)
+	}
+	return lock, err
+}
+
+// writeProjectLock escribe el lock en projectPath, que se crea si la plantilla
+// no tenía archivos que copiar
+func writeProjectLock(projectPath string, lock projectLock) error {
+	payload, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(projectPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(projectPath, projectLockFileName), append(payload, '\n'), 0644)
+}
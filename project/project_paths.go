@@ -0,0 +1,73 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func isTemplatedName(name string, d delimiters) bool {
+	return strings.Contains(name, d.Left)
+}
+
+func renderName(name string, r *renderer) (string, error) {
+	rendered, err := r.renderString("path", name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(rendered), nil
+}
+
+// renderPaths renames every file and directory below workdir whose name
+// contains template actions. Entries are processed deepest first so that a
+// directory is renamed only after its children. Names use the template's
+// global delimiters.
+func renderPaths(workdir string, r *renderer) error {
+	var paths []string
+	err := filepath.Walk(workdir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != workdir && isTemplatedName(info.Name(), r.delimiters) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return strings.Count(paths[i], string(filepath.Separator)) > strings.Count(paths[j], string(filepath.Separator))
+	})
+	for _, path := range paths {
+		name, err := renderName(filepath.Base(path), r)
+		if err != nil {
+			return fmt.Errorf("error rendering path %s: %w", path, err)
+		}
+		if name == "" {
+			return fmt.Errorf("path %s renders to an empty name", path)
+		}
+		target := filepath.Join(filepath.Dir(path), filepath.FromSlash(name))
+		if target == path {
+			continue
+		}
+		rel, err := filepath.Rel(workdir, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("path %s renders outside of the template: %s", path, name)
+		}
+		if _, err := os.Lstat(target); err == nil {
+			return fmt.Errorf("path %s renders to %s which already exists", path, name)
+		}
+		err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(path, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type fileStatus string
+
+const (
+	statusNew       fileStatus = "new"
+	statusOverwrite fileStatus = "overwrite"
+	statusSkip      fileStatus = "skip"
+	statusBackup    fileStatus = "backup"
+	statusConflict  fileStatus = "conflict"
+)
+
+// conflictPolicy define qué hacer con los archivos que ya existen en el
+// directorio de trabajo con contenido distinto.
+type conflictPolicy string
+
+const (
+	conflictFail      conflictPolicy = "fail"
+	conflictSkip      conflictPolicy = "skip"
+	conflictOverwrite conflictPolicy = "overwrite"
+	conflictPrompt    conflictPolicy = "prompt"
+	conflictBackup    conflictPolicy = "backup"
+)
+
+func parseConflictPolicy(value string) (conflictPolicy, error) {
+	switch policy := conflictPolicy(value); policy {
+	case conflictFail, conflictSkip, conflictOverwrite, conflictPrompt, conflictBackup:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid conflict policy %q: expected fail, skip, overwrite, prompt or backup", value)
+}
+
+// plannedFile es un archivo o directorio del proyecto renderizado y lo que
+// ocurrirá con él al copiarlo al directorio de trabajo. Los directorios se
+// incluyen para crear también los vacíos con los permisos de la plantilla.
+type plannedFile struct {
+	Path        string
+	Source      string
+	Destination string
+	Status      fileStatus
+	Dir         bool
+	Mode        os.FileMode
+	ModTime     time.Time
+}
+
+// planCopy compara los archivos renderizados en stagePath con los existentes
+// en projectPath. Los archivos idénticos y los directorios que ya existen se
+// marcan como skip.
+func planCopy(stagePath, projectPath string) ([]plannedFile, error) {
+	// Los hooks, las capas y los renombres pueden dejar enlaces que apuntan
+	// fuera del proyecto
+	err := checkSymlinks(stagePath)
+	if err != nil {
+		return nil, err
+	}
+	var plan []plannedFile
+	err = filepath.Walk(stagePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(stagePath, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		planned := plannedFile{
+			Path:        filepath.ToSlash(rel),
+			Source:      p,
+			Destination: filepath.Join(projectPath, rel),
+			Status:      statusNew,
+			Dir:         info.IsDir(),
+			Mode:        info.Mode(),
+			ModTime:     info.ModTime(),
+		}
+		if existing, err := os.Lstat(planned.Destination); err == nil {
+			planned.Status = statusOverwrite
+			if planned.Dir && existing.IsDir() || !planned.Dir && sameContent(planned.Source, planned.Destination) {
+				planned.Status = statusSkip
+			}
+		}
+		plan = append(plan, planned)
+		return nil
+	})
+	sort.Slice(plan, func(i, j int) bool {
+		return plan[i].Path < plan[j].Path
+	})
+	return plan, err
+}
+
+// printPlan muestra el árbol de archivos que se crearía con el estado de cada
+// archivo.
+func printPlan(out io.Writer, root string, plan []plannedFile) {
+	fmt.Fprintf(out, "%s/\n", root)
+	printed := make(map[string]bool)
+	counts := make(map[fileStatus]int)
+	for _, planned := range plan {
+		if planned.Dir {
+			parts := strings.Split(planned.Path, "/")
+			if !printed[planned.Path] {
+				printed[planned.Path] = true
+				fmt.Fprintf(out, "%s%s/\n", strings.Repeat("  ", len(parts)), parts[len(parts)-1])
+			}
+			continue
+		}
+		counts[planned.Status]++
+		parts := strings.Split(planned.Path, "/")
+		for i := range parts[:len(parts)-1] {
+			dir := strings.Join(parts[:i+1], "/")
+			if printed[dir] {
+				continue
+			}
+			printed[dir] = true
+			fmt.Fprintf(out, "%s%s/\n", strings.Repeat("  ", i+1), parts[i])
+		}
+		name := fmt.Sprintf("%s%s", strings.Repeat("  ", len(parts)), parts[len(parts)-1])
+		fmt.Fprintf(out, "%-50s %s\n", name, planned.Status)
+	}
+	summary := fmt.Sprintf("%d new, %d overwrite, %d skip", counts[statusNew], counts[statusOverwrite], counts[statusSkip])
+	if counts[statusBackup] > 0 {
+		summary += fmt.Sprintf(", %d backup", counts[statusBackup])
+	}
+	if counts[statusConflict] > 0 {
+		summary += fmt.Sprintf(", %d conflict", counts[statusConflict])
+	}
+	fmt.Fprintln(out, summary)
+}
+
+// resolvePlan aplica la política de conflictos a los archivos que serían
+// sobrescritos. Con la política prompt se pregunta por cada archivo usando
+// reader; si reader es nil los archivos quedan como overwrite.
+func resolvePlan(plan []plannedFile, policy conflictPolicy, reader *bufio.Reader, out io.Writer) error {
+	for i := range plan {
+		if plan[i].Status != statusOverwrite {
+			continue
+		}
+		switch policy {
+		case conflictFail:
+			plan[i].Status = statusConflict
+		case conflictSkip:
+			plan[i].Status = statusSkip
+		case conflictBackup:
+			plan[i].Status = statusBackup
+		case conflictPrompt:
+			if reader == nil {
+				continue
+			}
+			fmt.Fprintf(out, "%s already exists. Overwrite? [y/N] ", plan[i].Path)
+			answer, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer != "y" && answer != "yes" {
+				plan[i].Status = statusSkip
+			}
+		}
+	}
+	return nil
+}
+
+func conflictError(plan []plannedFile) error {
+	var conflicts []string
+	for _, planned := range plan {
+		if planned.Status == statusConflict {
+			conflicts = append(conflicts, planned.Path)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d files already exist: %s; use --conflict=skip, overwrite, prompt or backup", len(conflicts), strings.Join(conflicts, ", "))
+}
+
+func backupPath(destination string) string {
+	backup := destination + ".bak"
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(backup); err != nil {
+			return backup
+		}
+		backup = fmt.Sprintf("%s.bak.%d", destination, i)
+	}
+}
+
+// moveFile mueve src a dst y, si están en sistemas de archivos distintos,
+// copia el archivo.
+func moveFile(src, dst string) error {
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// touchFile actualiza la fecha de modificación de path, salvo que sea un
+// enlace simbólico.
+func touchFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(path, now, now)
+}
+
+// applyPlan mueve los archivos renderizados al directorio de trabajo y crea
+// los directorios nuevos. Si un archivo falla se eliminan los archivos y
+// directorios creados y se restauran los respaldos, dejando el directorio
+// como estaba. Los permisos y enlaces simbólicos se conservan siempre; las
+// fechas de modificación de la plantilla solo si preserveTimes es verdadero.
+func applyPlan(plan []plannedFile, preserveTimes bool, out io.Writer) (err error) {
+	if err := conflictError(plan); err != nil {
+		return err
+	}
+	var created []string
+	var createdDirs []plannedFile
+	backups := make(map[string]string)
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, p := range created {
+			os.Remove(p)
+		}
+		for destination, backup := range backups {
+			os.Rename(backup, destination)
+		}
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			os.Remove(createdDirs[i].Destination)
+		}
+	}()
+	for _, planned := range plan {
+		if planned.Status == statusSkip {
+			continue
+		}
+		err = os.MkdirAll(filepath.Dir(planned.Destination), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		switch planned.Status {
+		case statusBackup:
+			backup := backupPath(planned.Destination)
+			err = os.Rename(planned.Destination, backup)
+			if err != nil {
+				return err
+			}
+			backups[planned.Destination] = backup
+			fmt.Fprintf(out, "Backed up %s to %s\n", planned.Path, filepath.Base(backup))
+		case statusOverwrite:
+			backup := backupPath(planned.Destination)
+			err = os.Rename(planned.Destination, backup)
+			if err != nil {
+				return err
+			}
+			backups[planned.Destination] = backup
+			defer func(backup string) {
+				if err == nil {
+					os.Remove(backup)
+				}
+			}(backup)
+		}
+		if planned.Dir {
+			// Se crea con permisos de escritura para copiar su contenido; los
+			// de la plantilla se aplican al final
+			err = os.Mkdir(planned.Destination, os.ModePerm)
+			if err != nil {
+				return err
+			}
+			createdDirs = append(createdDirs, planned)
+			continue
+		}
+		err = moveFile(planned.Source, planned.Destination)
+		if err != nil {
+			return err
+		}
+		created = append(created, planned.Destination)
+		if !preserveTimes {
+			err = touchFile(planned.Destination)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// Del más profundo al más superficial para que la fecha de un directorio
+	// no cambie al ajustar la de sus subdirectorios
+	for i := len(createdDirs) - 1; i >= 0; i-- {
+		dir := createdDirs[i]
+		err = os.Chmod(dir.Destination, dir.Mode.Perm())
+		if err != nil {
+			return err
+		}
+		if preserveTimes {
+			err = os.Chtimes(dir.Destination, dir.ModTime, dir.ModTime)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printDiffs muestra un diff unificado de cada archivo existente cuyo
+// contenido cambiaría.
+func printDiffs(out io.Writer, plan []plannedFile) error {
+	for _, planned := range plan {
+		if planned.Dir || planned.Status == statusNew || planned.Status == statusSkip {
+			continue
+		}
+		if isBinaryFile(planned.Source) || isBinaryFile(planned.Destination) {
+			fmt.Fprintf(out, "Binary files a/%s and b/%s differ\n", planned.Path, planned.Path)
+			continue
+		}
+		err := writeUnifiedDiff(out, planned.Destination, planned.Source, "a/"+planned.Path, "b/"+planned.Path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeUnifiedDiff(out io.Writer, fromFile, toFile, fromName, toName string) error {
+	from, err := os.ReadFile(fromFile)
+	if err != nil {
+		return err
+	}
+	to, err := os.ReadFile(toFile)
+	if err != nil {
+		return err
+	}
+	return difflib.WriteUnifiedDiff(out, difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+}
@@ -0,0 +1,170 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/KaribuLab/kli/config"
+	"github.com/KaribuLab/kli/git"
+)
+
+const registryFileName = "kli-registry.json"
+
+var templateNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// isTemplateName indica si location es un nombre corto del registro y no una
+// URL o una ruta local.
+func isTemplateName(location string) bool {
+	if !templateNameRegex.MatchString(location) {
+		return false
+	}
+	_, err := os.Stat(location)
+	return err != nil
+}
+
+// loadRegistry retorna las plantillas de la configuración del usuario seguidas
+// de las de cada registro compartido. Un registro que no se puede leer se
+// informa en stderr y se omite.
+func loadRegistry(gitCmd git.Cmd, stderr io.Writer) ([]config.Template, error) {
+	userConfig, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	templates := append([]config.Template{}, userConfig.Templates...)
+	for _, location := range userConfig.Registries {
+		registry, err := fetchRegistry(gitCmd, location)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: error loading registry %s: %s\n", location, err)
+			continue
+		}
+		templates = append(templates, registry.Templates...)
+	}
+	return templates, nil
+}
+
+func readRegistry(payload []byte, location string) (*config.Registry, error) {
+	var registry config.Registry
+	err := json.Unmarshal(payload, &registry)
+	if err != nil {
+		return nil, fmt.Errorf("error reading registry %s: %w", location, err)
+	}
+	return &registry, nil
+}
+
+// fetchRegistry lee un registro desde un archivo JSON local o HTTP, un
+// directorio local o un repositorio Git que contenga kli-registry.json. De un
+// repositorio se usa el ref indicado con @ref o, si no hay, su rama por
+// defecto.
+func fetchRegistry(gitCmd git.Cmd, location string) (*config.Registry, error) {
+	if isHTTPURL(location) && strings.HasSuffix(strings.ToLower(location), ".json") {
+		response, err := httpClient.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error downloading %s: %s", location, response.Status)
+		}
+		payload, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+		return readRegistry(payload, location)
+	}
+	if info, err := os.Stat(location); err == nil {
+		registryPath := location
+		if info.IsDir() {
+			registryPath = filepath.Join(location, registryFileName)
+		}
+		payload, err := os.ReadFile(registryPath)
+		if err != nil {
+			return nil, err
+		}
+		return readRegistry(payload, location)
+	}
+	ref, err := parseTemplateRef(location)
+	if err != nil {
+		return nil, err
+	}
+	source, err := resolveSource(gitCmd, ref, "")
+	if err != nil {
+		return nil, err
+	}
+	tempPath, err := os.MkdirTemp("", "kli_*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempPath)
+	err = source.Fetch(tempPath)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := os.ReadFile(filepath.Join(tempPath, ref.Subdir, registryFileName))
+	if err != nil {
+		return nil, err
+	}
+	return readRegistry(payload, location)
+}
+
+func findTemplate(templates []config.Template, name string) (config.Template, bool) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return config.Template{}, false
+}
+
+// registryTemplateRef convierte una entrada del registro en una referencia.
+// El ref indicado por el usuario tiene prioridad sobre el del registro.
+func registryTemplateRef(entry config.Template, userRef string) (templateRef, error) {
+	ref, err := parseTemplateRef(entry.URL)
+	if err != nil {
+		return ref, err
+	}
+	if entry.Subdir != "" {
+		ref.Subdir = strings.Trim(entry.Subdir, "/")
+		if !isValidSubdir(ref.Subdir) {
+			return ref, fmt.Errorf("invalid subdirectory %q in template %s", entry.Subdir, entry.Name)
+		}
+	}
+	if entry.Ref != "" {
+		ref.Ref = entry.Ref
+	}
+	if userRef != "" {
+		ref.Ref = userRef
+	}
+	return ref, nil
+}
+
+// resolveTemplateRef interpreta el argumento de `kli project`. Los nombres
+// cortos se buscan en el registro, cuya rama se usa salvo que el usuario haya
+// indicado --branch.
+func resolveTemplateRef(gitCmd git.Cmd, arg string, branch string, branchChanged bool, stderr io.Writer) (templateRef, string, error) {
+	ref, err := parseTemplateRef(arg)
+	if err != nil {
+		return ref, branch, err
+	}
+	if ref.Subdir != "" || !isTemplateName(ref.Location) {
+		return ref, branch, nil
+	}
+	templates, err := loadRegistry(gitCmd, stderr)
+	if err != nil {
+		return ref, branch, err
+	}
+	entry, ok := findTemplate(templates, ref.Location)
+	if !ok {
+		return ref, branch, fmt.Errorf("template %s not found in registry", ref.Location)
+	}
+	if entry.Branch != "" && !branchChanged {
+		branch = entry.Branch
+	}
+	ref, err = registryTemplateRef(entry, ref.Ref)
+	return ref, branch, err
+}
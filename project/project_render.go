@@ -0,0 +1,231 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+var defaultDelimiters = delimiters{Left: "{{", Right: "}}"}
+
+// compiledOverride es un fileOverride con su glob ya compilado
+type compiledOverride struct {
+	pattern    pathPattern
+	delimiters *delimiters
+	raw        bool
+}
+
+// partial es una plantilla reutilizable que se puede incluir desde cualquier
+// archivo con {{template "nombre" .}}
+type partial struct {
+	name       string
+	text       string
+	delimiters delimiters
+}
+
+// renderer agrupa los valores de los prompts y la configuración de
+// delimitadores con la que se renderizan los archivos de la plantilla.
+type renderer struct {
+	prompt     projectPrompt
+	delimiters delimiters
+	overrides  []compiledOverride
+	partials   []partial
+	functions  texttemplate.FuncMap
+}
+
+func newRenderer(templatePath string, projectConfig projectConfig, prompt projectPrompt) (*renderer, error) {
+	r := &renderer{
+		prompt:     prompt,
+		delimiters: defaultDelimiters,
+		functions:  withInitialisms(projectConfig.Initialisms...).functions(),
+	}
+	if projectConfig.Delimiters != nil {
+		err := validateDelimiters(*projectConfig.Delimiters)
+		if err != nil {
+			return nil, err
+		}
+		r.delimiters = *projectConfig.Delimiters
+	}
+	for _, override := range projectConfig.Overrides {
+		if override.Delimiters != nil {
+			err := validateDelimiters(*override.Delimiters)
+			if err != nil {
+				return nil, fmt.Errorf("override %s: %w", override.Glob, err)
+			}
+		}
+		pattern, err := compilePattern(override.Glob)
+		if err != nil {
+			return nil, err
+		}
+		r.overrides = append(r.overrides, compiledOverride{
+			pattern:    pattern,
+			delimiters: override.Delimiters,
+			raw:        override.Raw,
+		})
+	}
+	// Solo hay parciales si la plantilla declara su directorio; un directorio
+	// partials/ sin declarar es un archivo más de la plantilla
+	if projectConfig.Partials == "" {
+		return r, nil
+	}
+	dir := filepath.Clean(filepath.FromSlash(projectConfig.Partials))
+	if filepath.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("partials directory %s must be inside the template", projectConfig.Partials)
+	}
+	err := r.loadPartials(templatePath, dir)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadPartials lee los archivos de dir. El nombre de cada parcial es su ruta
+// relativa a dir sin la última extensión: partials/license-header.tmpl se
+// incluye como "license-header" y partials/readme/intro.md como "readme/intro".
+func (r *renderer) loadPartials(templatePath string, dir string) error {
+	root := filepath.Join(templatePath, dir)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil
+	}
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		templateRel, err := filepath.Rel(templatePath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		r.partials = append(r.partials, partial{
+			name:       strings.TrimSuffix(rel, path.Ext(rel)),
+			text:       string(content),
+			delimiters: r.delimitersFor(templateRel, nil),
+		})
+		return nil
+	})
+}
+
+func validateDelimiters(d delimiters) error {
+	if strings.TrimSpace(d.Left) == "" || strings.TrimSpace(d.Right) == "" {
+		return fmt.Errorf("delimiters must define both left and right")
+	}
+	return nil
+}
+
+// delimitersFor retorna los delimitadores del archivo rel. Tienen prioridad
+// los definidos en la entrada del archivo, luego el último override que
+// coincide y por último los globales.
+func (r *renderer) delimitersFor(rel string, fileDelimiters *delimiters) delimiters {
+	if fileDelimiters != nil {
+		return *fileDelimiters
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	d := r.delimiters
+	for _, override := range r.overrides {
+		if override.delimiters != nil && override.pattern.Match(rel, false) {
+			d = *override.delimiters
+		}
+	}
+	return d
+}
+
+// isRaw indica si algún override marca rel para copiarse sin renderizar
+func (r *renderer) isRaw(rel string) bool {
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	for _, override := range r.overrides {
+		if override.raw && override.pattern.Match(rel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTemplate crea un conjunto de plantillas con los delimitadores d que ya
+// incluye todos los parciales
+func (r *renderer) newTemplate(name string, d delimiters) (*texttemplate.Template, error) {
+	tmpl := texttemplate.New(name).Funcs(r.functions).Delims(d.Left, d.Right)
+	for _, p := range r.partials {
+		_, err := tmpl.New(p.name).Delims(p.delimiters.Left, p.delimiters.Right).Parse(p.text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing partial %s: %w", p.name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// renderString renderiza text con los delimitadores globales
+func (r *renderer) renderString(name string, text string) (string, error) {
+	return r.renderText(name, text, r.delimiters)
+}
+
+func (r *renderer) renderText(name string, text string, d delimiters) (string, error) {
+	tmpl, err := r.newTemplate(name, d)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err = tmpl.Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	err = tmpl.Execute(&builder, r.prompt)
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// renderGlobs renderiza en su lugar los archivos que coinciden con alguno de
+// los globs. Los archivos binarios, los marcados como raw y los de skip, con
+// rutas relativas separadas por /, se dejan intactos.
+func renderGlobs(templatePath string, globs []string, skip map[string]bool, r *renderer) error {
+	if len(globs) == 0 {
+		return nil
+	}
+	patterns, err := compileGlobs(globs)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(templatePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(templatePath, p)
+		if err != nil {
+			return err
+		}
+		if isTemplateConfigFile(rel) || skip[filepath.ToSlash(rel)] || !matchesAny(patterns, rel) || r.isRaw(rel) || isBinaryFile(p) {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rendered, err := r.renderText(filepath.ToSlash(rel), string(content), r.delimitersFor(rel, nil))
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(p, []byte(rendered), info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		return preserveMetadata(p, info)
+	})
+}
@@ -0,0 +1,373 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// templateLiteral es un texto del proyecto original que se reemplaza por el
+// input Input en la plantilla generada
+type templateLiteral struct {
+	Literal     string
+	Input       string
+	Description string
+}
+
+// literalReplacement reemplaza text por la acción expr, que se escribe entre
+// los delimitadores de cada archivo
+type literalReplacement struct {
+	text string
+	expr string
+}
+
+// caseVariants son las variantes de mayúsculas que se buscan de cada literal,
+// en orden de preferencia cuando dos variantes producen el mismo texto
+var caseVariants = []struct {
+	transform func(string) string
+	expr      string
+}{
+	{ToKebabCase, "toKebabCase .Inputs.%s"},
+	{ToSnakeCase, "toSnakeCase .Inputs.%s"},
+	{ToPascalCase, "toPascalCase .Inputs.%s"},
+	{ToCamelCase, "toCamelCase .Inputs.%s"},
+	{ToConstantCase, "toConstantCase .Inputs.%s"},
+	{ToTitleCase, "toTitleCase .Inputs.%s"},
+	{func(s string) string { return strings.ToLower(ToPascalCase(s)) }, "toPascalCase .Inputs.%s | toLowerCase"},
+}
+
+// literalReplacements retorna los reemplazos de todas las variantes de los
+// literales, de la más larga a la más corta para que un literal que contiene
+// a otro tenga prioridad
+func literalReplacements(literals []templateLiteral) []literalReplacement {
+	seen := make(map[string]bool)
+	var replacements []literalReplacement
+	add := func(text string, expr string) {
+		if text == "" || seen[text] {
+			return
+		}
+		seen[text] = true
+		replacements = append(replacements, literalReplacement{text: text, expr: expr})
+	}
+	for _, literal := range literals {
+		for _, variant := range caseVariants {
+			add(variant.transform(literal.Literal), fmt.Sprintf(variant.expr, literal.Input))
+		}
+		// Si ninguna variante reproduce el literal se usa el input tal cual
+		add(literal.Literal, ".Inputs."+literal.Input)
+	}
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return len(replacements[i].text) > len(replacements[j].text)
+	})
+	return replacements
+}
+
+// replaceLiterals reemplaza en text los literales por acciones de plantilla
+// con los delimitadores d e indica si hubo algún reemplazo. Un literal solo se
+// reemplaza si empieza y termina en un límite de identificador, para que api
+// no cambie dentro de rapid o capital.
+func replaceLiterals(text string, replacements []literalReplacement, d delimiters) (string, bool) {
+	var result strings.Builder
+	changed := false
+	for i := 0; i < len(text); {
+		replaced := false
+		for _, replacement := range replacements {
+			end := i + len(replacement.text)
+			if strings.HasPrefix(text[i:], replacement.text) && isWordBoundary(text, i) && isWordBoundary(text, end) {
+				result.WriteString(d.Left + replacement.expr + d.Right)
+				i = end
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			changed = true
+			continue
+		}
+		result.WriteByte(text[i])
+		i++
+	}
+	return result.String(), changed
+}
+
+// isWordBoundary indica si la posición i de text separa dos identificadores:
+// uno de los lados no es letra ni dígito, o es un cambio de palabra en
+// camelCase (MyServiceHandler contiene MyService y APIClient contiene API).
+func isWordBoundary(text string, i int) bool {
+	if i == 0 || i == len(text) {
+		return true
+	}
+	before, after := rune(text[i-1]), rune(text[i])
+	if !isWordChar(before) || !isWordChar(after) {
+		return true
+	}
+	if !unicode.IsUpper(after) {
+		return false
+	}
+	if unicode.IsLower(before) || unicode.IsDigit(before) {
+		return true
+	}
+	return i+1 < len(text) && unicode.IsLower(rune(text[i+1]))
+}
+
+func isWordChar(r rune) bool {
+	return r >= utf8.RuneSelf || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// scaffoldDelimiters son los delimitadores que se prueban, en orden, para cada
+// archivo. Un archivo que ya contiene {{ (por ejemplo un workflow de GitHub
+// Actions o un chart de Helm) se renderiza con [[ ]].
+var scaffoldDelimiters = []delimiters{defaultDelimiters, {Left: "[[", Right: "]]"}, {Left: "<%", Right: "%>"}}
+
+func chooseDelimiters(content string) (delimiters, bool) {
+	for _, d := range scaffoldDelimiters {
+		if !strings.Contains(content, d.Left) && !strings.Contains(content, d.Right) {
+			return d, true
+		}
+	}
+	return delimiters{}, false
+}
+
+// escapeGlob escapa los caracteres especiales de los globs de .kliignore
+func escapeGlob(rel string) string {
+	var builder strings.Builder
+	for _, c := range rel {
+		if strings.ContainsRune(`*?[\`, c) {
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// scaffoldConfig es el .kliproject.json que genera template init
+type scaffoldConfig struct {
+	Schema      string         `json:"$schema"`
+	Description string         `json:"description"`
+	Prompts     []input        `json:"prompts"`
+	Render      []string       `json:"render,omitempty"`
+	Overrides   []fileOverride `json:"overrides,omitempty"`
+}
+
+// templateScaffolder copia un proyecto existente a una plantilla nueva
+type templateScaffolder struct {
+	projectPath  string
+	templatePath string
+	replacements []literalReplacement
+	ignore       *ignoreRules
+	config       scaffoldConfig
+	out          io.Writer
+}
+
+// scaffoldTemplate crea en templatePath una plantilla a partir del proyecto
+// en projectPath. Los literales se reemplazan en el contenido de los archivos
+// de texto y en los nombres de archivos y directorios. Se omiten .git y las
+// rutas que excluye el .gitignore del proyecto.
+func scaffoldTemplate(projectPath string, templatePath string, literals []templateLiteral, out io.Writer) error {
+	if len(literals) == 0 {
+		return fmt.Errorf("at least one literal to replace is required")
+	}
+	for _, literal := range literals {
+		if !isIdentifier(literal.Input) {
+			return fmt.Errorf("invalid input name %q", literal.Input)
+		}
+	}
+	if _, err := findProjectConfig(projectPath); !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s is already a template", projectPath)
+	}
+	source, err := filepath.Abs(projectPath)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.Abs(templatePath)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(source, target); err == nil && (rel == "." || !strings.HasPrefix(rel, "..")) {
+		return fmt.Errorf("the template cannot be created inside %s", projectPath)
+	}
+	if entries, err := os.ReadDir(templatePath); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", templatePath)
+	}
+	ignore, err := loadGitignore(projectPath)
+	if err != nil {
+		return err
+	}
+	s := templateScaffolder{
+		projectPath:  projectPath,
+		templatePath: templatePath,
+		replacements: literalReplacements(literals),
+		ignore:       ignore,
+		config: scaffoldConfig{
+			Schema:      "https://raw.githubusercontent.com/KaribuLab/kli/main/schema/kliproject.schema.json",
+			Description: fmt.Sprintf("Template generated from %s", filepath.Base(source)),
+		},
+		out: out,
+	}
+	for _, literal := range literals {
+		description := literal.Description
+		if description == "" {
+			description = ToTitleCase(literal.Input) + ":"
+		}
+		s.config.Prompts = append(s.config.Prompts, input{Name: literal.Input, Description: description, Type: "string"})
+	}
+	err = os.MkdirAll(templatePath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = filepath.Walk(projectPath, s.visit)
+	if err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(s.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(templatePath, projectConfigFileName), append(payload, '\n'), 0644)
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// loadGitignore lee el .gitignore de la raíz del proyecto, si existe
+func loadGitignore(projectPath string) (*ignoreRules, error) {
+	content, err := os.ReadFile(filepath.Join(projectPath, ".gitignore"))
+	if errors.Is(err, os.ErrNotExist) {
+		return parseIgnoreRules(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseIgnoreRules(strings.Split(string(content), "\n"))
+}
+
+// templateName retorna la ruta de rel en la plantilla, con los literales de
+// cada segmento reemplazados
+func (s *templateScaffolder) templateName(rel string) string {
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, segment := range segments {
+		segments[i], _ = replaceLiterals(segment, s.replacements, defaultDelimiters)
+	}
+	return strings.Join(segments, "/")
+}
+
+func (s *templateScaffolder) visit(p string, info os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(s.projectPath, p)
+	if err != nil || rel == "." {
+		return err
+	}
+	if info.IsDir() && info.Name() == ".git" || s.ignore.Ignored(filepath.ToSlash(rel), info.IsDir()) {
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	name := s.templateName(rel)
+	dst := filepath.Join(s.templatePath, filepath.FromSlash(name))
+	if name != filepath.ToSlash(rel) {
+		fmt.Fprintf(s.out, "Renamed %s to %s\n", filepath.ToSlash(rel), name)
+	}
+	switch {
+	case info.IsDir():
+		return os.MkdirAll(dst, info.Mode().Perm()|0700)
+	case info.Mode()&os.ModeSymlink != 0:
+		if _, err := resolveInRoot(s.projectPath, rel); errors.Is(err, errOutsideRoot) {
+			fmt.Fprintf(s.out, "Skipping %s: the symbolic link points outside of the project\n", filepath.ToSlash(rel))
+			return nil
+		}
+		return copySymlink(p, dst)
+	case !info.Mode().IsRegular():
+		return nil
+	case isBinaryFile(p):
+		return copyFile(p, dst)
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	d, ok := chooseDelimiters(string(content))
+	if !ok {
+		fmt.Fprintf(s.out, "Skipping %s: it already uses every supported delimiter\n", filepath.ToSlash(rel))
+		return copyFile(p, dst)
+	}
+	rendered, changed := replaceLiterals(string(content), s.replacements, d)
+	if !changed {
+		return copyFile(p, dst)
+	}
+	glob := "/" + escapeGlob(name)
+	s.config.Render = append(s.config.Render, glob)
+	if d != defaultDelimiters {
+		s.config.Overrides = append(s.config.Overrides, fileOverride{Glob: glob, Delimiters: &delimiters{Left: d.Left, Right: d.Right}})
+	}
+	fmt.Fprintf(s.out, "Templated %s\n", filepath.ToSlash(rel))
+	err = os.WriteFile(dst, []byte(rendered), info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return preserveMetadata(dst, info)
+}
+
+// askLiterals pregunta qué literales reemplazar hasta recibir una línea vacía
+func askLiterals(reader *bufio.Reader, out io.Writer) ([]templateLiteral, error) {
+	var literals []templateLiteral
+	for {
+		fmt.Fprintln(out, "Literal to replace with a prompt (empty to finish):")
+		text, err := reader.ReadString('\n')
+		text = strings.TrimSpace(text)
+		if text == "" {
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return literals, nil
+		}
+		fmt.Fprintf(out, "Input name for %q:\n", text)
+		name, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		fmt.Fprintf(out, "Prompt description (default %q):\n", ToTitleCase(strings.TrimSpace(name))+":")
+		description, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		literals = append(literals, templateLiteral{
+			Literal:     text,
+			Input:       strings.TrimSpace(name),
+			Description: strings.TrimSpace(description),
+		})
+	}
+}
+
+// parseLiteralFlags convierte los valores de --replace, con la forma
+// literal=input, en literales
+func parseLiteralFlags(values []string) ([]templateLiteral, error) {
+	var literals []templateLiteral
+	for _, value := range values {
+		literal, name, ok := strings.Cut(value, "=")
+		if !ok || literal == "" {
+			return nil, fmt.Errorf("invalid --replace %q, expected literal=input", value)
+		}
+		literals = append(literals, templateLiteral{Literal: literal, Input: name})
+	}
+	return literals, nil
+}
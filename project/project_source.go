@@ -0,0 +1,494 @@
+package project
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KaribuLab/kli/git"
+)
+
+// templateSource obtiene los archivos de una plantilla y los deja en un
+// directorio local.
+type templateSource interface {
+	Fetch(dst string) error
+	String() string
+}
+
+// templateRef es la referencia a una plantilla tal como la escribe el usuario:
+// <origen>[//<subdirectorio>][@<ref>], por ejemplo
+// https://github.com/org/templates//go/lambda@v1.2.0.
+type templateRef struct {
+	Location string
+	Subdir   string
+	Ref      string
+}
+
+func (r templateRef) String() string {
+	s := r.Location
+	if r.Subdir != "" {
+		s += "//" + r.Subdir
+	}
+	if r.Ref != "" {
+		s += "@" + r.Ref
+	}
+	return s
+}
+
+func parseTemplateRef(s string) (templateRef, error) {
+	ref := templateRef{Location: s}
+	if _, err := os.Stat(s); err == nil {
+		return ref, nil
+	}
+	if at := strings.LastIndex(s, "@"); at > 0 && !strings.ContainsAny(s[at+1:], "/:") {
+		ref.Ref = s[at+1:]
+		s = s[:at]
+		if ref.Ref == "" {
+			return ref, fmt.Errorf("empty ref in template %s", ref.Location)
+		}
+	}
+	start := 0
+	if scheme := strings.Index(s, "://"); scheme >= 0 {
+		start = scheme + len("://")
+	}
+	if sep := strings.Index(s[start:], "//"); sep >= 0 {
+		ref.Subdir = strings.Trim(s[start+sep+2:], "/")
+		s = s[:start+sep]
+		if !isValidSubdir(ref.Subdir) {
+			return ref, fmt.Errorf("invalid template subdirectory in %s", ref.Location)
+		}
+	}
+	ref.Location = s
+	return ref, nil
+}
+
+// isValidSubdir indica si subdir es una ruta relativa y limpia que no sale de
+// la raíz del origen
+func isValidSubdir(subdir string) bool {
+	if subdir == "" || subdir == "." || path.IsAbs(subdir) || filepath.IsAbs(subdir) || path.Clean(subdir) != subdir {
+		return false
+	}
+	for _, segment := range strings.FieldsFunc(subdir, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+type gitSource struct {
+	gitCmd     git.Cmd
+	repository string
+	ref        string
+	subdir     string
+	// commit es el commit clonado, disponible después de Fetch
+	commit string
+}
+
+func (s *gitSource) Fetch(dst string) error {
+	err := s.gitCmd.ShallowClone(git.GitClone{
+		Repository: s.repository,
+		Ref:        s.ref,
+		Subdir:     s.subdir,
+	}, dst)
+	if err != nil {
+		return err
+	}
+	s.commit, err = s.gitCmd.Run(false, "-C", dst, "rev-parse", "HEAD")
+	return err
+}
+
+func (s *gitSource) String() string {
+	return s.repository
+}
+
+type localSource struct {
+	path string
+}
+
+func (s *localSource) Fetch(dst string) error {
+	fmt.Println("Copying template from", s.path)
+	return copyAll(s.path, dst)
+}
+
+func (s *localSource) String() string {
+	return s.path
+}
+
+type archiveSource struct {
+	path string
+}
+
+func (s *archiveSource) Fetch(dst string) error {
+	fmt.Println("Extracting template from", s.path)
+	var err error
+	if strings.HasSuffix(strings.ToLower(s.path), ".zip") {
+		err = extractZip(s.path, dst)
+	} else {
+		err = extractTarGz(s.path, dst)
+	}
+	if err == nil {
+		err = checkSymlinks(dst)
+	}
+	if err != nil {
+		return fmt.Errorf("error extracting %s: %w", s.path, err)
+	}
+	return stripSingleRootDir(dst)
+}
+
+func (s *archiveSource) String() string {
+	return s.path
+}
+
+// httpClient descarga plantillas y registros. El timeout evita que un
+// servidor que deja de responder bloquee kli indefinidamente.
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+type httpSource struct {
+	url string
+}
+
+func (s *httpSource) Fetch(dst string) error {
+	fmt.Println("Downloading template from", s.url)
+	response, err := httpClient.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: %s", s.url, response.Status)
+	}
+	archive, err := os.CreateTemp("", "kli_*"+archiveExtension(s.url))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	_, err = io.Copy(archive, response.Body)
+	archive.Close()
+	if err != nil {
+		return err
+	}
+	return (&archiveSource{path: archive.Name()}).Fetch(dst)
+}
+
+func (s *httpSource) String() string {
+	return s.url
+}
+
+func archiveExtension(location string) string {
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		location = u.Path
+	}
+	lower := strings.ToLower(location)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+func isHTTPURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolveSource determina el tipo de origen de una plantilla: un directorio
+// local, un archivo .tar.gz/.zip local o remoto, o un repositorio Git. Para
+// Git se clona ref.Ref si está fijado y branch en caso contrario.
+func resolveSource(gitCmd git.Cmd, ref templateRef, branch string) (templateSource, error) {
+	gitRef := branch
+	if ref.Ref != "" {
+		gitRef = ref.Ref
+	}
+	newGitSource := func() templateSource {
+		return &gitSource{gitCmd: gitCmd, repository: ref.Location, ref: gitRef, subdir: ref.Subdir}
+	}
+	location := ref.Location
+	if isHTTPURL(location) {
+		if archiveExtension(location) == "" {
+			return newGitSource(), nil
+		}
+		if ref.Ref != "" {
+			return nil, fmt.Errorf("refs are only supported for Git templates: %s", ref)
+		}
+		return &httpSource{url: location}, nil
+	}
+	if info, err := os.Stat(location); err == nil {
+		if ref.Ref != "" {
+			return nil, fmt.Errorf("refs are only supported for Git templates: %s", ref)
+		}
+		absPath, err := filepath.Abs(location)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return &localSource{path: absPath}, nil
+		}
+		if archiveExtension(location) != "" {
+			return &archiveSource{path: absPath}, nil
+		}
+		return nil, fmt.Errorf("unsupported template file %s: expected a directory, .tar.gz or .zip", location)
+	}
+	return newGitSource(), nil
+}
+
+// safeJoin une name a dst y falla si el resultado queda fuera de dst.
+func safeJoin(dst string, name string) (string, error) {
+	target := filepath.Join(dst, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dst, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return target, nil
+}
+
+// errOutsideRoot indica que una ruta, siguiendo sus enlaces simbólicos, sale
+// del directorio que la contiene
+var errOutsideRoot = errors.New("path is outside of the directory")
+
+// maxSymlinks es la cantidad de enlaces que resolveInRoot sigue antes de
+// considerar que hay un ciclo
+const maxSymlinks = 255
+
+// resolveInRoot retorna la ruta real de name dentro de root, siguiendo los
+// enlaces simbólicos de cada segmento. Falla con errOutsideRoot si algún
+// segmento o enlace sale de root, incluso de forma transitoria. Los segmentos
+// que no existen se agregan tal cual.
+func resolveInRoot(root string, name string) (string, error) {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	var resolved []string
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", errOutsideRoot
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+		current := filepath.Join(root, filepath.Join(resolved...), part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			resolved = append(resolved, part)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = append(resolved, part)
+			continue
+		}
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many symbolic links in %s", name)
+		}
+		link, err := os.Readlink(current)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(link) || filepath.VolumeName(link) != "" {
+			return "", errOutsideRoot
+		}
+		parts = append(strings.Split(filepath.ToSlash(link), "/"), parts...)
+	}
+	return filepath.Join(root, filepath.Join(resolved...)), nil
+}
+
+// checkArchivePath falla si algún segmento de target bajo dst ya existe como
+// enlace simbólico, para que un archivo no pueda escribirse a través de un
+// enlace extraído antes
+func checkArchivePath(dst string, target string, name string) error {
+	rel, err := filepath.Rel(dst, target)
+	if err != nil {
+		return err
+	}
+	current := dst
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("invalid path in archive: %s goes through a symbolic link", name)
+		}
+	}
+	return nil
+}
+
+// checkSymlinks falla si algún enlace simbólico de dir apunta fuera de dir
+func checkSymlinks(dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		_, err = resolveInRoot(dir, rel)
+		if errors.Is(err, errOutsideRoot) {
+			return fmt.Errorf("symbolic link %s points outside of the template", filepath.ToSlash(rel))
+		}
+		return err
+	})
+}
+
+func writeArchiveFile(target string, info os.FileInfo, in io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return preserveMetadata(target, info)
+}
+
+func extractTarGz(archive string, dst string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := safeJoin(dst, header.Name)
+		if err == nil {
+			err = checkArchivePath(dst, target, header.Name)
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, os.ModePerm)
+		case tar.TypeReg:
+			err = writeArchiveFile(target, header.FileInfo(), reader)
+		case tar.TypeSymlink:
+			err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+			if err == nil {
+				err = os.Symlink(header.Linkname, target)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archive string, dst string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, f := range reader.File {
+		target, err := safeJoin(dst, f.Name)
+		if err == nil {
+			err = checkArchivePath(dst, target, f.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			err = os.MkdirAll(target, os.ModePerm)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		in, err := f.Open()
+		if err != nil {
+			return err
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			var linkname []byte
+			linkname, err = io.ReadAll(in)
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+			}
+			if err == nil {
+				err = os.Symlink(string(linkname), target)
+			}
+			in.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = writeArchiveFile(target, f.FileInfo(), in)
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripSingleRootDir mueve el contenido de dst un nivel hacia arriba cuando el
+// archivo contiene un único directorio raíz, como los tarballs de GitHub.
+func stripSingleRootDir(dst string) error {
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil
+	}
+	root := filepath.Join(dst, ".kli_root")
+	err = os.Rename(filepath.Join(dst, entries[0].Name()), root)
+	if err != nil {
+		return err
+	}
+	children, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		err = os.Rename(filepath.Join(root, child.Name()), filepath.Join(dst, child.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return os.Remove(root)
+}
@@ -0,0 +1,327 @@
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KaribuLab/kli/config"
+	"github.com/KaribuLab/kli/git"
+	"github.com/spf13/cobra"
+)
+
+func printTemplates(out io.Writer, templates []config.Template) {
+	if len(templates) == 0 {
+		fmt.Fprintln(out, "No templates found")
+		return
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tDESCRIPTION\tSOURCE")
+	for _, t := range templates {
+		ref, err := registryTemplateRef(t, "")
+		source := t.URL
+		if err == nil {
+			source = ref.String()
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", t.Name, t.Description, source)
+	}
+	writer.Flush()
+}
+
+func matchesTemplate(t config.Template, term string) bool {
+	term = strings.ToLower(term)
+	if strings.Contains(strings.ToLower(t.Name), term) || strings.Contains(strings.ToLower(t.Description), term) {
+		return true
+	}
+	for _, tag := range t.Tags {
+		if strings.Contains(strings.ToLower(tag), term) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTemplateListCommand(gitCmd git.Cmd) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the templates available in the registry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := loadRegistry(gitCmd, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			printTemplates(cmd.OutOrStdout(), templates)
+			return nil
+		},
+	}
+}
+
+func newTemplateSearchCommand(gitCmd git.Cmd) *cobra.Command {
+	return &cobra.Command{
+		Use:   "search <term>",
+		Short: "Search templates by name, description or tag",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := loadRegistry(gitCmd, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			var matches []config.Template
+			for _, t := range templates {
+				if matchesTemplate(t, args[0]) {
+					matches = append(matches, t)
+				}
+			}
+			printTemplates(cmd.OutOrStdout(), matches)
+			return nil
+		},
+	}
+}
+
+func newTemplateInfoCommand(gitCmd git.Cmd) *cobra.Command {
+	return &cobra.Command{
+		Use:   "info <name>",
+		Short: "Show the details and prompts of a template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := loadRegistry(gitCmd, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			entry, ok := findTemplate(templates, args[0])
+			if !ok {
+				return fmt.Errorf("template %s not found in registry", args[0])
+			}
+			ref, err := registryTemplateRef(entry, "")
+			if err != nil {
+				return err
+			}
+			branch := entry.Branch
+			if branch == "" {
+				branch = "main"
+			}
+			tempPath, err := os.MkdirTemp("", "kli_*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tempPath)
+			loaded, err := loadTemplate(gitCmd, ref, branch, fetchOptions{}, path.Join(tempPath, "source"), cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			projectConfig, err := loadProjectConfig(loaded.Path)
+			if err != nil {
+				return err
+			}
+			description := entry.Description
+			if description == "" {
+				description = projectConfig.Description
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Name:        %s\n", entry.Name)
+			fmt.Fprintf(out, "Description: %s\n", description)
+			fmt.Fprintf(out, "Source:      %s\n", ref)
+			if ref.Ref == "" {
+				fmt.Fprintf(out, "Branch:      %s\n", branch)
+			}
+			if len(entry.Tags) > 0 {
+				fmt.Fprintf(out, "Tags:        %s\n", strings.Join(entry.Tags, ", "))
+			}
+			fmt.Fprintln(out, "Prompts:")
+			writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			for _, prompt := range projectConfig.Prompts {
+				fmt.Fprintf(writer, "  %s\t%s\t%s\n", prompt.Name, prompt.Type, prompt.Description)
+			}
+			writer.Flush()
+			return nil
+		},
+	}
+}
+
+func newTemplateCacheCommand() *cobra.Command {
+	cacheCommand := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local template cache",
+	}
+	cacheCommand.AddCommand(&cobra.Command{
+		Use:   "ls",
+		Short: "List cached templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := openTemplateCache()
+			if err != nil {
+				return err
+			}
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(entries) == 0 {
+				fmt.Fprintln(out, "No cached templates")
+				return nil
+			}
+			writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(writer, "KEY\tSOURCE\tREF\tFETCHED\tSIZE")
+			for _, entry := range entries {
+				source := templateRef{Location: entry.Source, Subdir: entry.Subdir}
+				size := dirSize(cache.templatePath(entry.Key))
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d KB\n", entry.Key[:12], source, entry.Ref, entry.FetchedAt.Format(time.DateTime), (size+1023)/1024)
+			}
+			writer.Flush()
+			fmt.Fprintln(out, "Cache directory:", cache.dir)
+			return nil
+		},
+	})
+	pruneCommand := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			olderThan, err := cmd.Flags().GetDuration("older-than")
+			if err != nil {
+				return err
+			}
+			cache, err := openTemplateCache()
+			if err != nil {
+				return err
+			}
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+			removed := 0
+			for _, entry := range entries {
+				if olderThan > 0 && time.Since(entry.FetchedAt) < olderThan {
+					continue
+				}
+				err = cache.Remove(entry.Key)
+				if err != nil {
+					return err
+				}
+				removed++
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %d cached templates\n", removed)
+			return nil
+		},
+	}
+	pruneCommand.Flags().Duration("older-than", 0, "Only remove templates fetched before this duration (e.g. 720h)")
+	cacheCommand.AddCommand(pruneCommand)
+	return cacheCommand
+}
+
+func newTemplateValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Check the configuration and the files of a local template",
+		Long:  "Check that .kliproject.json is valid, that every source file exists, that the templates parse and that the inputs they use are declared in prompts",
+		Args:  cobra.MaximumNArgs(1),
+		// Los problemas ya se listan; la ayuda del comando solo los ocultaría
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templatePath := "."
+			if len(args) > 0 {
+				templatePath = args[0]
+			}
+			problems := validateTemplate(templatePath)
+			out := cmd.OutOrStdout()
+			if len(problems) == 0 {
+				fmt.Fprintf(out, "Template %s is valid\n", templatePath)
+				return nil
+			}
+			for _, problem := range problems {
+				fmt.Fprintf(out, "  - %s\n", problem)
+			}
+			return fmt.Errorf("template %s has %d problems", templatePath, len(problems))
+		},
+	}
+}
+
+func newTemplateTestCommand(gitCmd git.Cmd) *cobra.Command {
+	testCommand := &cobra.Command{
+		Use:   "test [path]",
+		Short: "Render the template fixtures and compare them with their golden output",
+		Long:  "Render the template with each answer file in .kli/fixtures, without running hooks, and compare the result with .kli/golden/<fixture>",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templatePath := "."
+			if len(args) > 0 {
+				templatePath = args[0]
+			}
+			update, err := cmd.Flags().GetBool("update")
+			if err != nil {
+				return err
+			}
+			return runTemplateTests(gitCmd, templatePath, update, cmd.OutOrStdout())
+		},
+	}
+	testCommand.Flags().Bool("update", false, "Replace the golden output with the current result")
+	return testCommand
+}
+
+func newTemplateInitCommand() *cobra.Command {
+	initCommand := &cobra.Command{
+		Use:   "init <project> [output]",
+		Short: "Create a template from an existing project",
+		Long:  "Copy the project to output (by default <project>-template) replacing the given literals, in all their cases, with prompts in the file contents and names, and generate its .kliproject.json",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectPath := args[0]
+			templatePath := ""
+			if len(args) > 1 {
+				templatePath = args[1]
+			} else {
+				absPath, err := filepath.Abs(projectPath)
+				if err != nil {
+					return err
+				}
+				templatePath = absPath + "-template"
+			}
+			replace, err := cmd.Flags().GetStringArray("replace")
+			if err != nil {
+				return err
+			}
+			literals, err := parseLiteralFlags(replace)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(literals) == 0 {
+				literals, err = askLiterals(bufio.NewReader(cmd.InOrStdin()), out)
+				if err != nil {
+					return err
+				}
+			}
+			err = scaffoldTemplate(projectPath, templatePath, literals, out)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Template created in %s\n", templatePath)
+			return nil
+		},
+	}
+	initCommand.Flags().StringArray("replace", nil, "Literal to replace with an input, as literal=input (can be repeated)")
+	return initCommand
+}
+
+func NewTemplateCommand(gitCmd git.Cmd) *cobra.Command {
+	templateCommand := &cobra.Command{
+		Use:   "template",
+		Short: "Manage project templates",
+	}
+	templateCommand.AddCommand(newTemplateListCommand(gitCmd))
+	templateCommand.AddCommand(newTemplateSearchCommand(gitCmd))
+	templateCommand.AddCommand(newTemplateInfoCommand(gitCmd))
+	templateCommand.AddCommand(newTemplateCacheCommand())
+	templateCommand.AddCommand(newTemplateValidateCommand())
+	templateCommand.AddCommand(newTemplateTestCommand(gitCmd))
+	templateCommand.AddCommand(newTemplateInitCommand())
+	return templateCommand
+}
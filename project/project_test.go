@@ -1 +1,2309 @@
 package project_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KaribuLab/kli/git"
+	mgit "github.com/KaribuLab/kli/mocks/github.com/KaribuLab/kli/git"
+	"github.com/KaribuLab/kli/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMain(m *testing.M) {
+	tempPath, err := os.MkdirTemp("", "kli_test_*")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("KLI_CACHE_DIR", filepath.Join(tempPath, "cache"))
+	os.Setenv("KLI_CONFIG", filepath.Join(tempPath, "config.json"))
+	code := m.Run()
+	os.RemoveAll(tempPath)
+	os.Exit(code)
+}
+
+func TestCaseConversion(t *testing.T) {
+	tests := []struct {
+		input    string
+		pascal   string
+		camel    string
+		snake    string
+		kebab    string
+		constant string
+		title    string
+	}{
+		{"my project", "MyProject", "myProject", "my_project", "my-project", "MY_PROJECT", "My Project"},
+		{"my-project_name", "MyProjectName", "myProjectName", "my_project_name", "my-project-name", "MY_PROJECT_NAME", "My Project Name"},
+		{"HTTPServer", "HTTPServer", "httpServer", "http_server", "http-server", "HTTP_SERVER", "HTTP Server"},
+		{"myAPIClient", "MyAPIClient", "myAPIClient", "my_api_client", "my-api-client", "MY_API_CLIENT", "My API Client"},
+		{"user_id", "UserID", "userID", "user_id", "user-id", "USER_ID", "User ID"},
+		{"parseURL", "ParseURL", "parseURL", "parse_url", "parse-url", "PARSE_URL", "Parse URL"},
+		{"ID", "ID", "id", "id", "id", "ID", "ID"},
+		{"v2Api", "V2API", "v2API", "v_2_api", "v-2-api", "V_2_API", "V 2 API"},
+		{"version10", "Version10", "version10", "version_10", "version-10", "VERSION_10", "Version 10"},
+		{"SimpleTest", "SimpleTest", "simpleTest", "simple_test", "simple-test", "SIMPLE_TEST", "Simple Test"},
+		{"ÁrbolBinario", "ÁrbolBinario", "árbolBinario", "árbol_binario", "árbol-binario", "ÁRBOL_BINARIO", "Árbol Binario"},
+		{"", "", "", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tt.pascal, project.ToPascalCase(tt.input))
+			assert.Equal(tt.camel, project.ToCamelCase(tt.input))
+			assert.Equal(tt.snake, project.ToSnakeCase(tt.input))
+			assert.Equal(tt.kebab, project.ToKebabCase(tt.input))
+			assert.Equal(tt.constant, project.ToConstantCase(tt.input))
+			assert.Equal(tt.title, project.ToTitleCase(tt.input))
+		})
+	}
+}
+
+func TestCustomInitialisms(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFiles(t, filepath.Join(dir, "grpc"), map[string]string{
+		".kliproject.json": `{"initialisms": ["grpc"], "render": ["name.txt"]}`,
+		"name.txt":         `{{toPascalCase "grpc-gateway"}} {{toCamelCase "grpc-gateway"}} {{title "grpc-gateway"}}`,
+	})
+	writeFiles(t, filepath.Join(dir, "plain"), map[string]string{
+		".kliproject.json": `{"render": ["name.txt"]}`,
+		"name.txt":         `{{toPascalCase "grpc-gateway"}}`,
+	})
+	_, err := runProject(t, "", filepath.Join(dir, "grpc"), "-w", "first")
+	assert.NoError(err)
+	content, _ := os.ReadFile(filepath.Join(dir, "first", "name.txt"))
+	assert.Equal("GRPCGateway grpcGateway GRPC Gateway", string(content))
+	// Las siglas de una plantilla no afectan a las siguientes
+	_, err = runProject(t, "", filepath.Join(dir, "plain"), "-w", "second")
+	assert.NoError(err)
+	content, _ = os.ReadFile(filepath.Join(dir, "second", "name.txt"))
+	assert.Equal("GrpcGateway", string(content))
+	assert.Equal("GrpcGateway", project.ToPascalCase("grpc-gateway"))
+}
+
+func TestPluralize(t *testing.T) {
+	tests := []struct {
+		singular string
+		plural   string
+	}{
+		{"user", "users"},
+		{"Category", "Categories"},
+		{"box", "boxes"},
+		{"knife", "knives"},
+		{"Wolf", "Wolves"},
+		{"child", "children"},
+		{"day", "days"},
+		{"data", "data"},
+		{"safe", "safes"},
+		{"roof", "roofs"},
+		{"archive", "archives"},
+		{"drive", "drives"},
+		{"move", "moves"},
+		{"bus", "buses"},
+		{"status", "statuses"},
+		{"database", "databases"},
+		{"class", "classes"},
+		{"branch", "branches"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.singular, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tt.plural, project.Pluralize(tt.singular))
+			assert.Equal(tt.singular, project.Singularize(tt.plural))
+		})
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+}
+
+func runProject(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	projectCmd := project.NewProjectCommand(git.NewGitCmd())
+	projectCmd.SetArgs(args)
+	projectCmd.SetIn(strings.NewReader(stdin))
+	output := new(bytes.Buffer)
+	projectCmd.SetOut(output)
+	projectCmd.SetErr(output)
+	projectCmd.SilenceUsage = true
+	err := projectCmd.Execute()
+	return output.String(), err
+}
+
+const sampleConfig = `{
+  "prompts": [{"name": "name", "description": "Project name:", "type": "string"}],
+  "templates": [{"rootDir": "templates", "delete": true, "files": [{"source": "templates/README.md.tmpl", "destination": "README.md"}]}]
+}`
+
+var sampleTemplate = map[string]string{
+	".kliproject.json":                         sampleConfig,
+	"templates/README.md.tmpl":                 "# {{toPascalCase .Inputs.name}}\n",
+	"cmd/{{toKebabCase .Inputs.name}}/main.go": "package main\n",
+	"{{toSnakeCase .Inputs.name}}.txt":         "static\n",
+}
+
+func assertSampleProject(t *testing.T, dir string) {
+	t.Helper()
+	assert := assert.New(t)
+	readme, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	assert.NoError(err)
+	assert.Equal("# MyService\n", string(readme))
+	assert.FileExists(filepath.Join(dir, "cmd", "my-service", "main.go"))
+	assert.FileExists(filepath.Join(dir, "my_service.txt"))
+	assert.NoFileExists(filepath.Join(dir, ".kliproject.json"))
+	assert.NoDirExists(filepath.Join(dir, "templates"))
+}
+
+func TestProjectFromLocalDirectory(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "my-service\n", templateDir, "-w", "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSampleProject(t, "out")
+}
+
+func TestProjectRendersPathNames(t *testing.T) {
+	config := `{"prompts": [{"name": "name", "description": "Name:"}, {"name": "dir", "description": "Dir:"}]}`
+	cases := []struct {
+		name     string
+		files    map[string]string
+		stdin    string
+		expected []string
+		err      string
+	}{
+		{
+			name: "nested",
+			files: map[string]string{
+				"{{.Inputs.dir}}/{{toSnakeCase .Inputs.name}}/{{toPascalCase .Inputs.name}}.go": "package x\n",
+				"{{.Inputs.dir}}/README.md": "readme\n",
+			},
+			stdin:    "my service\npkg\n",
+			expected: []string{"pkg/my_service/MyService.go", "pkg/README.md"},
+		},
+		{
+			name:  "escape",
+			files: map[string]string{"{{.Inputs.dir}}.txt": "x\n"},
+			stdin: "svc\n../../outside\n",
+			err:   "renders outside of the template",
+		},
+		{
+			name:  "collision",
+			files: map[string]string{"{{.Inputs.name}}.txt": "x\n", "svc.txt": "y\n"},
+			stdin: "svc\npkg\n",
+			err:   "renders to svc.txt which already exists",
+		},
+		{
+			name:  "empty",
+			files: map[string]string{"{{.Inputs.dir}}": "x\n"},
+			stdin: "svc\n\n",
+			err:   "renders to an empty name",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert.New(t)
+			templateDir := t.TempDir()
+			c.files[".kliproject.json"] = config
+			writeFiles(t, templateDir, c.files)
+			projectDir := filepath.Join(t.TempDir(), "project")
+			chdir(t, filepath.Dir(projectDir))
+			_, err := runProject(t, c.stdin, templateDir, "-w", "project")
+			if c.err != "" {
+				assert.ErrorContains(err, c.err)
+				assert.NoDirExists(projectDir)
+				return
+			}
+			assert.NoError(err)
+			for _, name := range c.expected {
+				assert.FileExists(filepath.Join(projectDir, filepath.FromSlash(name)))
+			}
+			entries, _ := os.ReadDir(projectDir)
+			for _, entry := range entries {
+				assert.NotContains(entry.Name(), "{{")
+			}
+		})
+	}
+}
+
+func TestProjectWithoutFilesCreatesWorkdir(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{".kliproject.json": `{"prompts": []}`})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", templateDir, "-w", "newproj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.FileExists(t, filepath.Join("newproj", ".kliproject.lock.json"))
+}
+
+func writeTarGz(t *testing.T, archivePath string, root string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+	for name, content := range files {
+		header := &tar.Header{Name: path.Join(root, name), Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeZip(t *testing.T, archivePath string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zw := zip.NewWriter(file)
+	defer zw.Close()
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProjectFromTarGz(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "template.tar.gz")
+	writeTarGz(t, archivePath, "template-v1.0.0", sampleTemplate)
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "my-service\n", archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSampleProject(t, ".")
+}
+
+func TestProjectFromHTTPZip(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "template.zip")
+	writeZip(t, archivePath, sampleTemplate)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, archivePath)
+	}))
+	defer server.Close()
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "my-service\n", server.URL+"/releases/template.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSampleProject(t, ".")
+}
+
+func TestProjectRejectsArchivePathTraversal(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "template.tar.gz")
+	writeTarGz(t, archivePath, "", map[string]string{"../evil.txt": "evil"})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", archivePath)
+	assert.ErrorContains(t, err, "invalid path in archive")
+}
+
+func TestProjectFromGitSubdirectoryAndRef(t *testing.T) {
+	cmd := mgit.NewMockCmd(t)
+	cmd.
+		EXPECT().
+		ShallowClone(git.GitClone{
+			Repository: "https://github.com/KaribuLab/templates.git",
+			Ref:        "v1.2.0",
+			Subdir:     "go/service",
+		}, mock.AnythingOfType("string")).
+		RunAndReturn(func(clone git.GitClone, workdir string) error {
+			writeFiles(t, filepath.Join(workdir, "go", "service"), sampleTemplate)
+			writeFiles(t, filepath.Join(workdir, "node"), map[string]string{"package.json": "{}"})
+			return nil
+		})
+	cmd.
+		EXPECT().
+		Run(false, "-C", mock.AnythingOfType("string"), "rev-parse", "HEAD").
+		Return("0123456789abcdef0123456789abcdef01234567", nil)
+	chdir(t, t.TempDir())
+	projectCmd := project.NewProjectCommand(cmd)
+	projectCmd.SetArgs([]string{"https://github.com/KaribuLab/templates.git//go/service@v1.2.0"})
+	projectCmd.SetIn(strings.NewReader("my-service\n"))
+	if err := projectCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	assertSampleProject(t, ".")
+	assert.NoFileExists(t, "package.json")
+	lock, err := os.ReadFile(".kliproject.lock.json")
+	assert.NoError(t, err)
+	assert.Contains(t, string(lock), `"commit": "0123456789abcdef0123456789abcdef01234567"`)
+	assert.Contains(t, string(lock), `"name": "my-service"`)
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.name=kli", "-c", "user.email=kli@example.com", "-c", "commit.gpgsign=false"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s", args, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestProjectFromGitPinnedCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q", "-b", "main")
+	writeFiles(t, filepath.Join(repo, "templates", "svc"), sampleTemplate)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-q", "-m", "feat: first template")
+	commit := gitRun(t, repo, "rev-parse", "HEAD")
+	gitRun(t, repo, "tag", "v1.0.0")
+	// Una rama cuyo nombre parece un SHA se clona como rama
+	gitRun(t, repo, "branch", "deadbeef")
+	writeFiles(t, filepath.Join(repo, "templates", "svc"), map[string]string{"templates/README.md.tmpl": "changed\n"})
+	gitRun(t, repo, "commit", "-q", "-am", "feat: change template")
+
+	for _, ref := range []string{commit, "v1.0.0", "deadbeef"} {
+		t.Run(ref, func(t *testing.T) {
+			chdir(t, t.TempDir())
+			_, err := runProject(t, "my-service\n", "file://"+repo+"//templates/svc@"+ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSampleProject(t, ".")
+		})
+	}
+}
+
+func TestProjectFromLocalSubdirectory(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, filepath.Join(templateDir, "templates", "svc"), sampleTemplate)
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "my-service\n", templateDir+"//templates/svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSampleProject(t, ".")
+	_, err = runProject(t, "", templateDir+"//missing")
+	assert.ErrorContains(t, err, "template directory missing not found")
+}
+
+func writeUserConfig(t *testing.T, content string) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	writeFiles(t, filepath.Dir(configPath), map[string]string{"config.json": content})
+	t.Setenv("KLI_CONFIG", configPath)
+}
+
+func setupRegistry(t *testing.T) string {
+	t.Helper()
+	templatesDir := t.TempDir()
+	writeFiles(t, filepath.Join(templatesDir, "go", "service"), sampleTemplate)
+	registryDir := t.TempDir()
+	writeFiles(t, registryDir, map[string]string{
+		"kli-registry.json": `{"templates": [{"name": "go-service", "description": "Go HTTP service", "url": "` + filepath.ToSlash(templatesDir) + `", "subdir": "go/service", "tags": ["go", "http"]}]}`,
+	})
+	writeUserConfig(t, `{
+  "templates": [{"name": "local-service", "description": "Local service", "url": "`+filepath.ToSlash(templatesDir)+`//go/service"}],
+  "registries": ["`+filepath.ToSlash(registryDir)+`"]
+}`)
+	return templatesDir
+}
+
+func runTemplate(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	templateCmd := project.NewTemplateCommand(git.NewGitCmd())
+	templateCmd.SetArgs(args)
+	output := new(bytes.Buffer)
+	templateCmd.SetOut(output)
+	templateCmd.SetErr(output)
+	templateCmd.SilenceUsage = true
+	err := templateCmd.Execute()
+	return output.String(), err
+}
+
+func TestProjectFromRegistryName(t *testing.T) {
+	setupRegistry(t)
+	for _, name := range []string{"go-service", "local-service"} {
+		t.Run(name, func(t *testing.T) {
+			chdir(t, t.TempDir())
+			_, err := runProject(t, "my-service\n", name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSampleProject(t, ".")
+		})
+	}
+	_, err := runProject(t, "", "unknown-template")
+	assert.ErrorContains(t, err, "template unknown-template not found in registry")
+
+	templatesDir := t.TempDir()
+	writeUserConfig(t, `{"templates": [{"name": "escape", "url": "`+filepath.ToSlash(templatesDir)+`", "subdir": "go/../../.."}]}`)
+	_, err = runProject(t, "", "escape")
+	assert.ErrorContains(t, err, `invalid subdirectory "go/../../.." in template escape`)
+}
+
+func TestTemplateListFromGitRegistry(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	assert := assert.New(t)
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q", "-b", "master")
+	writeFiles(t, repo, map[string]string{"kli-registry.json": `{"templates": [{"name": "on-master", "url": "https://example.com/master"}]}`})
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-q", "-m", "feat: registry")
+	gitRun(t, repo, "checkout", "-q", "-b", "release")
+	writeFiles(t, repo, map[string]string{"kli-registry.json": `{"templates": [{"name": "on-release", "url": "https://example.com/release"}]}`})
+	gitRun(t, repo, "commit", "-q", "-am", "feat: release registry")
+	gitRun(t, repo, "checkout", "-q", "master")
+
+	// Sin ref se usa la rama por defecto del repositorio
+	writeUserConfig(t, `{"registries": ["file://`+filepath.ToSlash(repo)+`"]}`)
+	output, err := runTemplate(t, "list")
+	assert.NoError(err)
+	assert.Contains(output, "on-master")
+
+	writeUserConfig(t, `{"registries": ["file://`+filepath.ToSlash(repo)+`@release"]}`)
+	output, err = runTemplate(t, "list")
+	assert.NoError(err)
+	assert.Contains(output, "on-release")
+	assert.NotContains(output, "on-master")
+}
+
+func TestTemplateListSearchInfo(t *testing.T) {
+	assert := assert.New(t)
+	setupRegistry(t)
+	output, err := runTemplate(t, "list")
+	assert.NoError(err)
+	assert.Contains(output, "go-service")
+	assert.Contains(output, "local-service")
+
+	output, err = runTemplate(t, "search", "HTTP")
+	assert.NoError(err)
+	assert.Contains(output, "go-service")
+	assert.NotContains(output, "local-service")
+
+	output, err = runTemplate(t, "info", "go-service")
+	assert.NoError(err)
+	assert.Contains(output, "Go HTTP service")
+	assert.Contains(output, "go, http")
+	assert.Contains(output, "Project name:")
+}
+
+func TestProjectTemplateCache(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("KLI_CACHE_DIR", t.TempDir())
+	archivePath := filepath.Join(t.TempDir(), "template.zip")
+	writeZip(t, archivePath, sampleTemplate)
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		http.ServeFile(w, r, archivePath)
+	}))
+	url := server.URL + "/template.zip"
+
+	_, err := runProject(t, "", url, "--offline", "-w", t.TempDir())
+	assert.ErrorContains(err, "is not cached")
+
+	for _, args := range [][]string{{url}, {url}, {url, "--refresh"}} {
+		chdir(t, t.TempDir())
+		_, err := runProject(t, "my-service\n", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSampleProject(t, ".")
+	}
+	assert.Equal(2, downloads)
+
+	server.Close()
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "my-service\n", url, "--offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSampleProject(t, ".")
+
+	output, err := runTemplate(t, "cache", "ls")
+	assert.NoError(err)
+	assert.Contains(output, url)
+
+	output, err = runTemplate(t, "cache", "prune", "--older-than", "1h")
+	assert.NoError(err)
+	assert.Contains(output, "Removed 0 cached templates")
+
+	output, err = runTemplate(t, "cache", "prune")
+	assert.NoError(err)
+	assert.Contains(output, "Removed 1 cached templates")
+
+	output, err = runTemplate(t, "cache", "ls")
+	assert.NoError(err)
+	assert.Contains(output, "No cached templates")
+}
+
+func TestProjectUpdate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	assert := assert.New(t)
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q", "-b", "main")
+	writeFiles(t, repo, map[string]string{
+		".kliproject.json":       `{"prompts": [{"name": "name", "description": "Project name:"}], "templates": [{"rootDir": "templates", "delete": true, "files": [{"source": "templates/main.go.tmpl", "destination": "main.go"}]}]}`,
+		"templates/main.go.tmpl": "package main\n\n// {{.Inputs.name}}\n\nfunc main() {\n}\n",
+		"config.yaml":            "port: 8080\n",
+		"obsolete.txt":           "old\n",
+		"untouched.txt":          "same\n",
+	})
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-q", "-m", "feat: v1")
+
+	projectDir := t.TempDir()
+	chdir(t, projectDir)
+	_, err := runProject(t, "svc\n", "file://"+repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cambios locales: uno compatible con la plantilla y otro en conflicto
+	writeFiles(t, projectDir, map[string]string{
+		"main.go":     "package main\n\n// svc\n\nfunc main() {\n}\n\nfunc helper() {}\n",
+		"config.yaml": "port: 9090\n",
+	})
+
+	writeFiles(t, repo, map[string]string{
+		".kliproject.json":       `{"prompts": [{"name": "name", "description": "Project name:"}, {"name": "owner", "description": "Owner:"}], "templates": [{"rootDir": "templates", "delete": true, "files": [{"source": "templates/main.go.tmpl", "destination": "main.go"}]}]}`,
+		"templates/main.go.tmpl": "package main\n\n// {{.Inputs.name}} by {{.Inputs.owner}}\n\nfunc main() {\n}\n",
+		"config.yaml":            "port: 8081\n",
+		"added.txt":              "new\n",
+	})
+	gitRun(t, repo, "rm", "-q", "obsolete.txt")
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-q", "-m", "feat: v2")
+	commit := gitRun(t, repo, "rev-parse", "HEAD")
+
+	projectCmd := project.NewProjectCommand(git.NewGitCmd())
+	projectCmd.SetArgs([]string{"update"})
+	projectCmd.SetIn(strings.NewReader("team\n"))
+	output := new(bytes.Buffer)
+	projectCmd.SetOut(output)
+	if err := projectCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(output.String())
+
+	mainGo, _ := os.ReadFile("main.go")
+	assert.Equal("package main\n\n// svc by team\n\nfunc main() {\n}\n\nfunc helper() {}\n", string(mainGo))
+	configYaml, _ := os.ReadFile("config.yaml")
+	assert.Contains(string(configYaml), "<<<<<<< local")
+	assert.Contains(string(configYaml), "port: 8081")
+	assert.FileExists("added.txt")
+	assert.NoFileExists("obsolete.txt")
+	assert.FileExists("untouched.txt")
+	assert.Contains(output.String(), "conflict  config.yaml")
+	lock, _ := os.ReadFile(".kliproject.lock.json")
+	assert.Contains(string(lock), commit)
+	assert.Contains(string(lock), `"owner": "team"`)
+}
+
+func TestProjectUpdateMovesToLatestTagAndRunsActions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	assert := assert.New(t)
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q", "-b", "main")
+	release := func(version string, tag string) string {
+		writeFiles(t, repo, map[string]string{
+			".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "render": ["name.txt"],
+  "hooks": {"postrender": [{"name": "stamp", "command": "echo ` + version + ` > hook.txt"}]},
+  "actions": [{"type": "rename", "from": "name.txt", "to": "{{.Inputs.name}}.txt"}]
+}`,
+			"name.txt": "{{.Inputs.name}} " + version + "\n",
+		})
+		gitRun(t, repo, "add", "-A")
+		gitRun(t, repo, "commit", "-q", "-m", "feat: "+version)
+		if tag != "" {
+			gitRun(t, repo, "tag", tag)
+		}
+		return gitRun(t, repo, "rev-parse", "HEAD")
+	}
+	first := release("v1", "v1.0.0")
+	release("v2", "v1.10.0")
+	release("v3", "v1.9.0")
+	release("v4", "")
+
+	runUpdate := func(args ...string) (string, error) {
+		projectCmd := project.NewProjectCommand(git.NewGitCmd())
+		projectCmd.SetArgs(append([]string{"update", "--yes"}, args...))
+		projectCmd.SetIn(strings.NewReader(""))
+		output := new(bytes.Buffer)
+		projectCmd.SetOut(output)
+		projectCmd.SetErr(output)
+		err := projectCmd.Execute()
+		return output.String(), err
+	}
+	read := func(name string) string {
+		content, _ := os.ReadFile(name)
+		return string(content)
+	}
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", "file://"+repo+"@v1.0.0", "--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("svc v1\n", read("svc.txt"))
+	assert.Equal("v1\n", read("hook.txt"))
+
+	// Sin --ref se aplica el tag más reciente y no el último commit de la
+	// rama. Las acciones y los hooks postrender se aplican a ambas versiones.
+	output, err := runUpdate()
+	assert.NoError(err)
+	assert.Contains(output, "Applying v1.10.0")
+	assert.Equal("svc v2\n", read("svc.txt"))
+	assert.Equal("v2\n", read("hook.txt"))
+	assert.NoFileExists("name.txt")
+	assert.Contains(read(".kliproject.lock.json"), `"ref": "v1.10.0"`)
+
+	_, err = runUpdate("--ref", "main")
+	assert.NoError(err)
+	assert.Equal("svc v4\n", read("svc.txt"))
+	assert.Contains(read(".kliproject.lock.json"), `"ref": "main"`)
+
+	// Un commit fijo requiere --ref
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "svc\n", "file://"+repo+"@"+first, "--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = runUpdate()
+	assert.ErrorContains(err, "the project is pinned to "+first+", use --ref")
+	assert.Equal("svc v1\n", read("svc.txt"))
+}
+
+func TestProjectUpdateRejectsLocalTemplates(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "my-service\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = runProject(t, "", "update")
+	assert.ErrorContains(t, err, "is not a Git template, only templates from a Git repository can be updated")
+}
+
+func TestProjectUpdateUsesLockedLayers(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	assert := assert.New(t)
+	layerRepo := t.TempDir()
+	gitRun(t, layerRepo, "init", "-q", "-b", "main")
+	release := func(version string) string {
+		writeFiles(t, layerRepo, map[string]string{
+			".kliproject.json": `{"prompts": []}`,
+			"db.sql":           "-- " + version + "\n",
+		})
+		gitRun(t, layerRepo, "add", "-A")
+		gitRun(t, layerRepo, "commit", "-q", "-m", "feat: "+version)
+		return gitRun(t, layerRepo, "rev-parse", "HEAD")
+	}
+	first := release("v1")
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q", "-b", "main")
+	writeFiles(t, repo, map[string]string{
+		".kliproject.json": `{"prompts": [], "layers": [{"source": "file://` + layerRepo + `"}]}`,
+		"README.md":        "# svc\n",
+	})
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-q", "-m", "feat: template")
+
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", "file://"+repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, _ := os.ReadFile(".kliproject.lock.json")
+	assert.Contains(string(lock), `"commit": "`+first+`"`)
+
+	// La base se renderiza con la versión registrada de la capa, así el
+	// cambio de la capa se aplica al proyecto
+	second := release("v2")
+	projectCmd := project.NewProjectCommand(git.NewGitCmd())
+	projectCmd.SetArgs([]string{"update"})
+	projectCmd.SetIn(strings.NewReader(""))
+	output := new(bytes.Buffer)
+	projectCmd.SetOut(output)
+	if err := projectCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(output.String(), "updated   db.sql")
+	content, _ := os.ReadFile("db.sql")
+	assert.Equal("-- v2\n", string(content))
+	lock, _ = os.ReadFile(".kliproject.lock.json")
+	assert.Contains(string(lock), `"commit": "`+second+`"`)
+}
+
+func TestProjectRejectsLayerHooks(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		"base/.kliproject.json":    `{"prompts": [], "hooks": {"postcopy": [{"name": "setup", "command": "touch ran.txt"}]}}`,
+		"variant/.kliproject.json": `{"prompts": [], "layers": [{"source": "../base"}]}`,
+		"variant/README.md":        "# variant\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", filepath.Join(templateDir, "variant"), "--yes")
+	assert.ErrorContains(err, "hooks are not supported in layers")
+	assert.NoFileExists("ran.txt")
+	assert.NoFileExists("README.md")
+	output, err := runTemplate(t, "validate", filepath.Join(templateDir, "variant"))
+	assert.Error(err)
+	assert.Contains(output, "layers[0]: hooks are not supported in layers")
+}
+
+func TestProjectDryRunAndDiff(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+	projectDir := t.TempDir()
+	writeFiles(t, projectDir, map[string]string{
+		"README.md":      "# Existing\n",
+		"my_service.txt": "static\n",
+	})
+	chdir(t, projectDir)
+
+	output, err := runProject(t, "my-service\n", templateDir, "--dry-run")
+	assert.NoError(err)
+	assert.Regexp(`README\.md\s+conflict`, output)
+	assert.Contains(output, "1 new, 0 overwrite, 1 skip, 1 conflict")
+
+	output, err = runProject(t, "my-service\n", templateDir, "--dry-run", "--conflict=overwrite")
+	assert.NoError(err)
+	assert.Regexp(`README\.md\s+overwrite`, output)
+	assert.Regexp(`my_service\.txt\s+skip`, output)
+	assert.Regexp(`cmd/\n\s+my-service/\n\s+main\.go\s+new`, output)
+	assert.Contains(output, "1 new, 1 overwrite, 1 skip")
+	assert.NotContains(output, "@@")
+	assert.NoDirExists("cmd")
+
+	output, err = runProject(t, "my-service\n", templateDir, "--diff")
+	assert.NoError(err)
+	assert.Contains(output, "--- a/README.md\n+++ b/README.md\n")
+	assert.Contains(output, "-# Existing\n+# MyService\n")
+	assert.NoDirExists("cmd")
+	assert.NoFileExists(".kliproject.lock.json")
+	readme, _ := os.ReadFile("README.md")
+	assert.Equal("# Existing\n", string(readme))
+}
+
+func TestProjectDryRunSkipsHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh on this test")
+	}
+	assert := assert.New(t)
+	marker := filepath.Join(t.TempDir(), "ran.txt")
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{"render": ["README.md"], "hooks": {
+  "preprompt": [{"name": "preprompt", "command": "echo preprompt >> ` + marker + ` && echo acme", "output": "owner"}],
+  "prerender": [{"name": "prerender", "command": "echo prerender >> ` + marker + `"}],
+  "postrender": [{"name": "postrender", "command": "echo postrender >> ` + marker + `"}]
+}}`,
+		"README.md": "# svc by {{.Inputs.owner}}\n",
+	})
+	chdir(t, t.TempDir())
+	for _, flag := range []string{"--dry-run", "--diff"} {
+		output, err := runProject(t, "", templateDir, flag)
+		assert.NoError(err)
+		assert.Contains(output, "Skipping 3 hooks")
+		assert.Contains(output, "warning: the preview does not run hooks, input owner computed by hook 'preprompt' is missing")
+		assert.NoFileExists(marker)
+	}
+	_, err := runProject(t, "", templateDir)
+	assert.NoError(err)
+	content, _ := os.ReadFile(marker)
+	assert.Equal("preprompt\nprerender\npostrender\n", string(content))
+	content, _ = os.ReadFile("README.md")
+	assert.Equal("# svc by acme\n", string(content))
+}
+
+func TestProjectConflictPolicies(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+	existing := map[string]string{
+		"README.md":        "# Existing\n",
+		".kliproject.json": "user file\n",
+	}
+	tests := []struct {
+		policy string
+		stdin  string
+		readme string
+		err    string
+		backup bool
+	}{
+		{policy: "fail", readme: "# Existing\n", err: "1 files already exist: README.md"},
+		{policy: "skip", readme: "# Existing\n"},
+		{policy: "overwrite", readme: "# MyService\n"},
+		{policy: "prompt", stdin: "y\n", readme: "# MyService\n"},
+		{policy: "prompt", stdin: "n\n", readme: "# Existing\n"},
+		{policy: "backup", readme: "# MyService\n", backup: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy+tt.stdin, func(t *testing.T) {
+			assert := assert.New(t)
+			projectDir := t.TempDir()
+			writeFiles(t, projectDir, existing)
+			chdir(t, projectDir)
+			output, err := runProject(t, "my-service\n"+tt.stdin, templateDir, "--conflict="+tt.policy)
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				assert.NoDirExists("cmd")
+			} else {
+				assert.NoError(err)
+				assert.FileExists(filepath.Join("cmd", "my-service", "main.go"))
+			}
+			readme, _ := os.ReadFile("README.md")
+			assert.Equal(tt.readme, string(readme))
+			userFile, _ := os.ReadFile(".kliproject.json")
+			assert.Equal("user file\n", string(userFile))
+			if tt.backup {
+				backup, _ := os.ReadFile("README.md.bak")
+				assert.Equal("# Existing\n", string(backup))
+				assert.Contains(output, "Backed up README.md to README.md.bak")
+			} else {
+				assert.NoFileExists("README.md.bak")
+			}
+		})
+	}
+	_, err := runProject(t, "", templateDir, "--conflict=merge")
+	assert.ErrorContains(t, err, "invalid conflict policy")
+}
+
+func writeModeTemplate(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes and symlinks are not supported on windows")
+	}
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json":        `{"prompts": [{"name": "name", "description": "Project name:"}], "templates": [{"rootDir": "templates", "delete": true, "files": [{"source": "templates/build.sh.tmpl", "destination": "scripts/build.sh"}]}]}`,
+		"templates/build.sh.tmpl": "#!/bin/sh\necho {{.Inputs.name}}\n",
+		"scripts/run.sh":          "#!/bin/sh\necho run\n",
+		"README.md":               "# Readme\n",
+		"private/key.txt":         "secret\n",
+	})
+	if err := os.Mkdir(filepath.Join(templateDir, "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(templateDir, "private"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, script := range []string{"templates/build.sh.tmpl", "scripts/run.sh"} {
+		if err := os.Chmod(filepath.Join(templateDir, script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("README.md", filepath.Join(templateDir, "docs.md")); err != nil {
+		t.Fatal(err)
+	}
+	oldTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, name := range []string{"templates/build.sh.tmpl", "scripts/run.sh", "README.md"} {
+		if err := os.Chtimes(filepath.Join(templateDir, name), oldTime, oldTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return templateDir
+}
+
+func TestProjectPreservesModesAndSymlinks(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := writeModeTemplate(t)
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, script := range []string{"scripts/run.sh", "scripts/build.sh"} {
+		info, err := os.Stat(script)
+		if assert.NoError(err) {
+			assert.Equal(os.FileMode(0755), info.Mode().Perm(), script)
+			assert.True(time.Since(info.ModTime()) < time.Hour, script)
+		}
+	}
+	info, err := os.Stat("README.md")
+	if assert.NoError(err) {
+		assert.Equal(os.FileMode(0644), info.Mode().Perm())
+	}
+	// Los directorios vacíos se crean y los directorios conservan sus permisos
+	assert.DirExists("logs")
+	info, err = os.Stat("private")
+	if assert.NoError(err) {
+		assert.Equal(os.FileMode(0700), info.Mode().Perm())
+	}
+	assert.FileExists(filepath.Join("private", "key.txt"))
+	target, err := os.Readlink("docs.md")
+	assert.NoError(err)
+	assert.Equal("README.md", target)
+}
+
+func TestProjectRejectsEscapingSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported on windows")
+	}
+	outside := t.TempDir()
+	for name, target := range map[string]string{"absolute": outside, "relative": "../../../../../../../../../.." + outside} {
+		t.Run(name, func(t *testing.T) {
+			templateDir := t.TempDir()
+			writeFiles(t, templateDir, sampleTemplate)
+			if err := os.Symlink(target, filepath.Join(templateDir, "link")); err != nil {
+				t.Fatal(err)
+			}
+			chdir(t, t.TempDir())
+			_, err := runProject(t, "svc\n", templateDir)
+			assert.ErrorContains(t, err, "symbolic link link points outside of the template")
+			assert.NoFileExists(t, "README.md")
+		})
+	}
+
+	t.Run("template init", func(t *testing.T) {
+		assert := assert.New(t)
+		projectDir := t.TempDir()
+		writeFiles(t, projectDir, map[string]string{"main.go": "package svc\n"})
+		if err := os.Symlink(outside, filepath.Join(projectDir, "external")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("main.go", filepath.Join(projectDir, "entry.go")); err != nil {
+			t.Fatal(err)
+		}
+		templateDir := filepath.Join(t.TempDir(), "template")
+		output, err := runTemplate(t, "init", projectDir, templateDir, "--replace", "svc=name")
+		assert.NoError(err)
+		assert.Contains(output, "Skipping external: the symbolic link points outside of the project")
+		_, err = os.Lstat(filepath.Join(templateDir, "external"))
+		assert.True(os.IsNotExist(err))
+		target, err := os.Readlink(filepath.Join(templateDir, "entry.go"))
+		assert.NoError(err)
+		assert.Equal("main.go", target)
+	})
+}
+
+func TestProjectPreserveTimes(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := writeModeTemplate(t)
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir, "--preserve-times")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, name := range []string{"scripts/run.sh", "scripts/build.sh", "README.md"} {
+		info, err := os.Stat(name)
+		if assert.NoError(err) {
+			assert.True(oldTime.Equal(info.ModTime()), "%s: %s", name, info.ModTime())
+		}
+	}
+}
+
+func TestProjectPreservesModesFromTarGz(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes and symlinks are not supported on windows")
+	}
+	assert := assert.New(t)
+	archivePath := filepath.Join(t.TempDir(), "template.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	entries := []*tar.Header{
+		{Name: ".kliproject.json", Mode: 0644, Size: 2, Typeflag: tar.TypeReg},
+		{Name: "run.sh", Mode: 0755, Size: 10, Typeflag: tar.TypeReg},
+		{Name: "link.sh", Linkname: "run.sh", Typeflag: tar.TypeSymlink},
+	}
+	contents := []string{"{}", "echo run\n\n", ""}
+	for i, header := range entries {
+		assert.NoError(tw.WriteHeader(header))
+		_, err := tw.Write([]byte(contents[i]))
+		assert.NoError(err)
+	}
+	tw.Close()
+	gz.Close()
+	file.Close()
+
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "", archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat("run.sh")
+	if assert.NoError(err) {
+		assert.Equal(os.FileMode(0755), info.Mode().Perm())
+	}
+	target, err := os.Readlink("link.sh")
+	assert.NoError(err)
+	assert.Equal("run.sh", target)
+}
+
+func TestProjectRejectsMaliciousArchives(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported on windows")
+	}
+	outside := t.TempDir()
+	writeTar := func(t *testing.T, headers []*tar.Header) string {
+		archivePath := filepath.Join(t.TempDir(), "template.tar.gz")
+		file, err := os.Create(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		gz := gzip.NewWriter(file)
+		defer gz.Close()
+		tw := tar.NewWriter(gz)
+		defer tw.Close()
+		for _, header := range headers {
+			content := ""
+			if header.Typeflag == tar.TypeReg {
+				content = "pwned\n"
+				header.Size = int64(len(content))
+				header.Mode = 0644
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return archivePath
+	}
+	writeSymlinkZip := func(t *testing.T) string {
+		archivePath := filepath.Join(t.TempDir(), "template.zip")
+		file, err := os.Create(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		zw := zip.NewWriter(file)
+		defer zw.Close()
+		header := &zip.FileHeader{Name: "evil"}
+		header.SetMode(os.ModeSymlink | 0777)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(outside))
+		w, err = zw.Create("evil/pwned.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("pwned\n"))
+		return archivePath
+	}
+	tests := []struct {
+		name    string
+		archive func(t *testing.T) string
+	}{
+		{"write through absolute symlink", func(t *testing.T) string {
+			return writeTar(t, []*tar.Header{
+				{Name: "evil", Linkname: outside, Typeflag: tar.TypeSymlink},
+				{Name: "evil/pwned.txt", Typeflag: tar.TypeReg},
+			})
+		}},
+		{"write through relative symlink", func(t *testing.T) string {
+			return writeTar(t, []*tar.Header{
+				{Name: "up", Linkname: "../../../../../../../../../../" + outside, Typeflag: tar.TypeSymlink},
+				{Name: "up/pwned.txt", Typeflag: tar.TypeReg},
+			})
+		}},
+		{"replace file with symlink", func(t *testing.T) string {
+			return writeTar(t, []*tar.Header{
+				{Name: "pwned.txt", Linkname: filepath.Join(outside, "pwned.txt"), Typeflag: tar.TypeSymlink},
+				{Name: "pwned.txt", Typeflag: tar.TypeReg},
+			})
+		}},
+		{"symlink to parent", func(t *testing.T) string {
+			return writeTar(t, []*tar.Header{
+				{Name: ".kliproject.json", Typeflag: tar.TypeReg},
+				{Name: "secret", Linkname: "../secret", Typeflag: tar.TypeSymlink},
+			})
+		}},
+		{"symlink through another symlink", func(t *testing.T) string {
+			return writeTar(t, []*tar.Header{
+				{Name: ".kliproject.json", Typeflag: tar.TypeReg},
+				{Name: "a", Linkname: "b/../secret", Typeflag: tar.TypeSymlink},
+				{Name: "b", Linkname: ".", Typeflag: tar.TypeSymlink},
+			})
+		}},
+		{"zip write through symlink", writeSymlinkZip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := tt.archive(t)
+			chdir(t, t.TempDir())
+			_, err := runProject(t, "", archivePath)
+			assert.Error(t, err)
+			assert.NoFileExists(t, filepath.Join(outside, "pwned.txt"))
+		})
+	}
+}
+
+func TestProjectRendersTemplateFilesOnce(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "render": ["*.md"],
+  "templates": [{"rootDir": "templates", "delete": true, "files": [{"source": "templates/README.md.tmpl", "destination": "README.md"}]}]
+}`,
+		"templates/README.md.tmpl": "# {{.Inputs.name}}\n\nUsage: {{\"{{.Inputs.name}}\"}}\n",
+		"CHANGELOG.md":             "# {{.Inputs.name}}\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme, _ := os.ReadFile("README.md")
+	assert.Equal("# svc\n\nUsage: {{.Inputs.name}}\n", string(readme))
+	changelog, _ := os.ReadFile("CHANGELOG.md")
+	assert.Equal("# svc\n", string(changelog))
+}
+
+func TestProjectIgnoreRulesAndBinaryFiles(t *testing.T) {
+	assert := assert.New(t)
+	binary := "\x89PNG\r\n\x00\x00{{ not a template }}"
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "render": ["**/*.go", "assets/*"],
+  "templates": [{"rootDir": "templates", "delete": true, "files": [
+    {"source": "templates/README.md.tmpl", "destination": "README.md"},
+    {"source": "templates/icon.png", "destination": "icon.png"},
+    {"source": "templates/app.tmpl", "destination": "app.log"}
+  ]}]
+}`,
+		".kliignore":               "# comentarios\ndocs/\n*.log\n!keep.log\n/root-only.txt\n**/tmp/**\n/svc.txt\n",
+		"templates/app.tmpl":       "{{.Inputs.name}} log\n",
+		"{{.Inputs.name}}.txt":     "renamed\n",
+		"templates/README.md.tmpl": "# {{.Inputs.name}}\n",
+		"templates/icon.png":       binary,
+		"main.go":                  "package {{.Inputs.name}}\n",
+		"internal/app/app.go":      "// {{toPascalCase .Inputs.name}}\n",
+		"notes.txt":                "{{ left as is }}\n",
+		"assets/logo.png":          binary,
+		"docs/guide.md":            "guide\n",
+		"debug.log":                "log\n",
+		"keep.log":                 "keep\n",
+		"root-only.txt":            "ignored\n",
+		"nested/root-only.txt":     "kept\n",
+		"nested/tmp/cache/a.txt":   "ignored\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		assert.NoError(err, name)
+		return string(content)
+	}
+	assert.Equal("# svc\n", read("README.md"))
+	assert.Equal("package svc\n", read("main.go"))
+	assert.Equal("// Svc\n", read(filepath.Join("internal", "app", "app.go")))
+	assert.Equal("{{ left as is }}\n", read("notes.txt"))
+	assert.Equal(binary, read(filepath.Join("assets", "logo.png")))
+	assert.Equal(binary, read("icon.png"))
+	assert.Equal("keep\n", read("keep.log"))
+	// Los patrones se comparan con las rutas de la plantilla, no con los
+	// nombres renderizados
+	assert.Equal("svc log\n", read("app.log"))
+	assert.Equal("renamed\n", read("svc.txt"))
+	assert.Equal("kept\n", read(filepath.Join("nested", "root-only.txt")))
+	assert.NoDirExists("docs")
+	assert.NoFileExists("debug.log")
+	assert.NoFileExists("root-only.txt")
+	assert.NoDirExists(filepath.Join("nested", "tmp", "cache"))
+	assert.NoFileExists(".kliignore")
+}
+
+func TestProjectCustomDelimitersAndRawFiles(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "delimiters": {"left": "[[", "right": "]]"},
+  "render": [".github/**", "chart/**", "Makefile"],
+  "overrides": [
+    {"glob": "Makefile", "delimiters": {"left": "<%", "right": "%>"}},
+    {"glob": "chart/templates/**", "raw": true}
+  ],
+  "templates": [{"rootDir": "templates", "delete": true, "files": [
+    {"source": "templates/README.md.tmpl", "destination": "[[.Inputs.name]]/README.md"},
+    {"source": "templates/main.go.tmpl", "destination": "main.go", "delimiters": {"left": "{{", "right": "}}"}},
+    {"source": "templates/deploy.yaml", "destination": "deploy.yaml", "raw": true}
+  ]}]
+}`,
+		"templates/README.md.tmpl":       "# [[.Inputs.name]]\n",
+		"templates/main.go.tmpl":         "package {{.Inputs.name}}\n",
+		"templates/deploy.yaml":          "image: [[ .Values.image ]]\n",
+		".github/workflows/ci.yml":       "name: [[.Inputs.name]]\ntoken: ${{ secrets.TOKEN }}\n",
+		"chart/values.yaml":              "name: [[.Inputs.name]]\n",
+		"chart/templates/deployment.yml": "name: {{ .Release.Name }} [[ raw ]]\n",
+		"Makefile":                       "NAME=<%.Inputs.name%>\nTAG=[[tag]]\n",
+		"[[.Inputs.name]].txt":           "dynamic\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		assert.NoError(err, name)
+		return string(content)
+	}
+	assert.Equal("# svc\n", read(filepath.Join("svc", "README.md")))
+	assert.Equal("package svc\n", read("main.go"))
+	assert.Equal("image: [[ .Values.image ]]\n", read("deploy.yaml"))
+	assert.Equal("name: svc\ntoken: ${{ secrets.TOKEN }}\n", read(filepath.Join(".github", "workflows", "ci.yml")))
+	assert.Equal("name: svc\n", read(filepath.Join("chart", "values.yaml")))
+	assert.Equal("name: {{ .Release.Name }} [[ raw ]]\n", read(filepath.Join("chart", "templates", "deployment.yml")))
+	assert.Equal("NAME=svc\nTAG=[[tag]]\n", read("Makefile"))
+	assert.Equal("dynamic\n", read("svc.txt"))
+}
+
+func TestProjectRejectsIncompleteDelimiters(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{"delimiters": {"left": "[["}}`,
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", templateDir)
+	assert.ErrorContains(t, err, "delimiters must define both left and right")
+}
+
+func TestProjectPartials(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "render": ["**/*.go"],
+  "partials": "partials",
+  "overrides": [{"glob": "partials/readme/**", "delimiters": {"left": "[[", "right": "]]"}}],
+  "templates": [{"rootDir": "templates", "delete": true, "files": [
+    {"source": "templates/README.md.tmpl", "destination": "README.md"}
+  ]}]
+}`,
+		"partials/license-header.tmpl": "// Copyright {{.Inputs.name}}\n",
+		"partials/readme/intro.md":     "Welcome to [[.Inputs.name]]\n",
+		"templates/README.md.tmpl":     "# {{.Inputs.name}}\n{{template \"readme/intro\" .}}{{template \"license-header\" .}}",
+		"main.go":                      "{{template \"license-header\" .}}package main\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile("README.md")
+	assert.NoError(err)
+	assert.Equal("# svc\nWelcome to svc\n// Copyright svc\n", string(content))
+	content, err = os.ReadFile("main.go")
+	assert.NoError(err)
+	assert.Equal("// Copyright svc\npackage main\n", string(content))
+	assert.NoDirExists("partials")
+}
+
+func TestProjectKeepsUndeclaredPartialsDir(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json":     `{"render": ["**/*.go"]}`,
+		"partials/partials.go": "package partials\n",
+		"main.go":              "package main\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join("partials", "partials.go"))
+	assert.NoError(err)
+	assert.Equal("package partials\n", string(content))
+}
+
+func TestProjectRejectsPartialsOutsideTemplate(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{"partials": "../shared"}`,
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", templateDir)
+	assert.ErrorContains(t, err, "must be inside the template")
+}
+
+func TestProjectPosthooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh on this test")
+	}
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}, {"name": "db", "description": "Database:"}],
+  "posthooks": [
+    {"name": "shell", "command": "echo \"hello {{.Inputs.name}}\" | tr a-z A-Z > shell.txt && echo $GREETING > env.txt", "env": {"GREETING": "hi {{.Inputs.name}}"}},
+    {"name": "argv", "args": ["sh", "-c", "pwd > where.txt"], "workdir": "sub"},
+    {"name": "postgres", "command": "touch postgres.txt", "when": "{{eq .Inputs.db \"postgres\"}}"},
+    {"name": "mysql", "command": "touch mysql.txt", "when": "{{eq .Inputs.db \"mysql\"}}"},
+    {"name": "optional", "command": "exit 3", "continueOnError": true},
+    {"name": "last", "command": "touch last.txt"}
+  ]
+}`,
+		"sub/.keep": "",
+	})
+	chdir(t, t.TempDir())
+	output, err := runProject(t, "svc\nmysql\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		assert.NoError(err, name)
+		return strings.TrimSpace(string(content))
+	}
+	assert.Equal("HELLO SVC", read("shell.txt"))
+	assert.Equal("hi svc", read("env.txt"))
+	assert.True(strings.HasSuffix(read(filepath.Join("sub", "where.txt")), "sub"))
+	assert.NoFileExists("postgres.txt")
+	assert.FileExists("mysql.txt")
+	assert.FileExists("last.txt")
+	assert.Contains(output, "Skipping postcopy hook 'postgres'")
+	assert.Contains(output, "warning: hook optional failed")
+}
+
+func TestProjectHookShellQuote(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh on this test")
+	}
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}, {"name": "module", "description": "Module:"}, {"name": "empty", "description": "Empty:"}],
+  "posthooks": [{"name": "quote", "command": "printf '%s|%s|%s' {{shellQuote .Inputs.name}} {{shellQuote .Inputs.module}} {{shellQuote .Inputs.empty}} > name.txt"}]
+}`,
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "it's $(touch a.txt); touch b.txt\ngithub.com/acme/svc\n\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile("name.txt")
+	assert.NoError(err)
+	assert.Equal("it's $(touch a.txt); touch b.txt|github.com/acme/svc|", string(content))
+	assert.NoFileExists("a.txt")
+	assert.NoFileExists("b.txt")
+}
+
+func TestProjectPosthookErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh on this test")
+	}
+	cases := map[string]string{
+		`{"name": "slow", "command": "sleep 5", "timeout": "100ms"}`: "hook slow timed out after 100ms",
+		`{"name": "fail", "command": "exit 2"}`:                      "hook fail failed",
+		`{"name": "escape", "command": "true", "workdir": "../.."}`:  "is outside of",
+		`{"name": "both", "command": "true", "args": ["true"]}`:      "mutually exclusive",
+		`{"name": "duration", "command": "true", "timeout": "soon"}`: "invalid timeout",
+		`{"name": "empty"}`: "command or args is required",
+	}
+	for hook, expected := range cases {
+		templateDir := t.TempDir()
+		writeFiles(t, templateDir, map[string]string{
+			".kliproject.json": `{"posthooks": [` + hook + `]}`,
+		})
+		chdir(t, t.TempDir())
+		_, err := runProject(t, "", templateDir)
+		assert.ErrorContains(t, err, expected, hook)
+	}
+}
+
+func TestProjectLifecycleHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh on this test")
+	}
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}, {"name": "owner", "description": "Owner:"}],
+  "render": ["README.md"],
+  "hooks": {
+    "preprompt": [{"name": "owner", "command": "echo acme", "output": "owner"}],
+    "prerender": [{"name": "schema", "command": "echo '{\"name\": \"{{.Inputs.name}}\"}' > schema.json"}],
+    "postrender": [{"name": "format", "command": "tr a-z A-Z < README.md > README.tmp && mv README.tmp README.md"}],
+    "postcopy": [{"name": "second", "command": "echo second >> order.txt"}]
+  },
+  "posthooks": [{"name": "first", "command": "echo first >> order.txt"}]
+}`,
+		"README.md": "# {{.Inputs.name}} by {{.Inputs.owner}}\n",
+	})
+	chdir(t, t.TempDir())
+	output, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		assert.NoError(err, name)
+		return string(content)
+	}
+	assert.Equal("# SVC BY ACME\n", read("README.md"))
+	assert.Equal("{\"name\": \"svc\"}\n", read("schema.json"))
+	assert.Equal("first\nsecond\n", read("order.txt"))
+	assert.Contains(output, "Running postrender hook 'format'")
+	lock, err := os.ReadFile(".kliproject.lock.json")
+	assert.NoError(err)
+	assert.Contains(string(lock), `"owner": "acme"`)
+}
+
+func TestProjectHookTrust(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use sh on this test")
+	}
+	assert := assert.New(t)
+	archiveDir := t.TempDir()
+	archivePath := filepath.Join(archiveDir, "template.tar.gz")
+	writeTarGz(t, archivePath, "template", map[string]string{
+		".kliproject.json": `{"posthooks": [{"name": "mark", "command": "touch ran.txt"}]}`,
+	})
+	run := func(stdin string, args ...string) (string, error) {
+		chdir(t, t.TempDir())
+		return runProject(t, stdin, append([]string{archivePath}, args...)...)
+	}
+
+	writeUserConfig(t, `{}`)
+	_, err := run("")
+	assert.ErrorContains(err, "require confirmation")
+	assert.NoFileExists("ran.txt")
+
+	output, err := run("n\n")
+	assert.NoError(err)
+	assert.Contains(output, "postcopy mark: touch ran.txt")
+	assert.Contains(output, "Skipping 1 hooks")
+	assert.NoFileExists("ran.txt")
+
+	_, err = run("", "--no-hooks")
+	assert.NoError(err)
+	assert.NoFileExists("ran.txt")
+
+	_, err = run("", "--yes")
+	assert.NoError(err)
+	assert.FileExists("ran.txt")
+
+	_, err = run("y\n")
+	assert.NoError(err)
+	assert.FileExists("ran.txt")
+
+	_, err = run("a\n")
+	assert.NoError(err)
+	assert.FileExists("ran.txt")
+	_, err = run("")
+	assert.NoError(err)
+	assert.FileExists("ran.txt")
+	userConfig, err := os.ReadFile(os.Getenv("KLI_CONFIG"))
+	assert.NoError(err)
+	assert.Contains(string(userConfig), "trustedSources")
+
+	writeUserConfig(t, `{"trustedSources": ["`+filepath.ToSlash(archiveDir)+`/"]}`)
+	_, err = run("")
+	assert.NoError(err)
+	assert.FileExists("ran.txt")
+}
+
+func TestProjectActions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "kli")
+	t.Setenv("GIT_AUTHOR_EMAIL", "kli@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "kli")
+	t.Setenv("GIT_COMMITTER_EMAIL", "kli@example.com")
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "actions": [
+    {"type": "rename", "from": "gitignore", "to": ".gitignore"},
+    {"type": "move", "from": "src/app.js", "to": "lib/{{.Inputs.name}}.js"},
+    {"type": "delete", "path": "tmp/*.log"},
+    {"type": "chmod", "path": "scripts/*.sh", "mode": "0755"},
+    {"type": "appendFile", "path": ".gitignore", "content": "{{.Inputs.name}}.env\n"},
+    {"type": "jsonPatch", "path": "package.json", "patch": [
+      {"op": "replace", "path": "/name", "value": "{{.Inputs.name}}"},
+      {"op": "add", "path": "/scripts/test", "value": "jest"},
+      {"op": "remove", "path": "/private"},
+      {"op": "add", "path": "/keywords/-", "value": "kli"}
+    ]},
+    {"type": "yamlSet", "path": "config.yaml", "key": "server.port", "value": 9090},
+    {"type": "yamlSet", "path": "config.yaml", "key": "app.name", "value": "{{.Inputs.name}}"},
+    {"type": "delete", "path": "never.txt", "when": "false"},
+    {"type": "gitInit", "branch": "trunk", "message": "feat: scaffold {{.Inputs.name}}"}
+  ]
+}`,
+		"gitignore":        "node_modules/\n",
+		"src/app.js":       "module.exports = {}\n",
+		"tmp/debug.log":    "log\n",
+		"tmp/keep.txt":     "keep\n",
+		"scripts/build.sh": "#!/bin/sh\n",
+		"never.txt":        "still here\n",
+		"package.json":     "{\n    \"name\": \"template\",\n    \"version\": \"1.0.0\",\n    \"private\": true,\n    \"scripts\": {\n        \"start\": \"node lib/index.js\"\n    },\n    \"keywords\": [\"a\"]\n}\n",
+		"config.yaml":      "# servidor\nserver:\n  host: localhost\n  port: 8080 # puerto\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\n", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		assert.NoError(err, name)
+		return string(content)
+	}
+	assert.Equal("node_modules/\nsvc.env\n", read(".gitignore"))
+	assert.NoFileExists("gitignore")
+	assert.Equal("module.exports = {}\n", read(filepath.Join("lib", "svc.js")))
+	assert.NoFileExists(filepath.Join("tmp", "debug.log"))
+	assert.FileExists(filepath.Join("tmp", "keep.txt"))
+	assert.FileExists("never.txt")
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join("scripts", "build.sh"))
+		if assert.NoError(err) {
+			assert.Equal(os.FileMode(0755), info.Mode().Perm())
+		}
+	}
+	assert.Equal("{\n    \"name\": \"svc\",\n    \"version\": \"1.0.0\",\n    \"scripts\": {\n        \"start\": \"node lib/index.js\",\n        \"test\": \"jest\"\n    },\n    \"keywords\": [\n        \"a\",\n        \"kli\"\n    ]\n}\n", read("package.json"))
+	assert.Equal("# servidor\nserver:\n  host: localhost\n  port: 9090 # puerto\napp:\n  name: svc\n", read("config.yaml"))
+	assert.Equal("trunk", gitRun(t, ".", "branch", "--show-current"))
+	assert.Equal("feat: scaffold svc", gitRun(t, ".", "log", "-1", "--pretty=%s"))
+	assert.Empty(gitRun(t, ".", "status", "--porcelain"))
+}
+
+func TestProjectActionsStayInsideProject(t *testing.T) {
+	for _, action := range []string{
+		`{"type": "delete", "path": "../*"}`,
+		`{"type": "move", "from": "a.txt", "to": "../a.txt"}`,
+		`{"type": "appendFile", "path": "/etc/hosts", "content": "x"}`,
+		`{"type": "delete", "path": "."}`,
+	} {
+		templateDir := t.TempDir()
+		writeFiles(t, templateDir, map[string]string{
+			".kliproject.json": `{"actions": [` + action + `]}`,
+			"a.txt":            "a\n",
+		})
+		chdir(t, t.TempDir())
+		_, err := runProject(t, "", templateDir)
+		assert.ErrorContains(t, err, "outside of the project", action)
+	}
+}
+
+func TestProjectActionsDoNotFollowSymlinksOutside(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported on windows")
+	}
+	outside := t.TempDir()
+	writeFiles(t, outside, map[string]string{
+		"secret.txt":  "secret\n",
+		"config.yaml": "port: 1\n",
+		"keep.txt":    "keep\n",
+	})
+	for _, action := range []string{
+		`{"type": "appendFile", "path": "shared/app.env", "content": "x"}`,
+		`{"type": "appendFile", "path": "secret.txt", "content": "x"}`,
+		`{"type": "chmod", "path": "secret.txt", "mode": "0777"}`,
+		`{"type": "insert", "path": "secret.txt", "anchor": "secret", "content": "x"}`,
+		`{"type": "jsonPatch", "path": "shared/secret.txt", "patch": []}`,
+		`{"type": "yamlSet", "path": "shared/config.yaml", "key": "port", "value": 2}`,
+		`{"type": "delete", "path": "shared/*"}`,
+		`{"type": "move", "from": "shared/keep.txt", "to": "keep.txt"}`,
+	} {
+		templateDir := t.TempDir()
+		writeFiles(t, templateDir, map[string]string{
+			".kliproject.json": `{"actions": [` + action + `]}`,
+			"a.txt":            "a\n",
+		})
+		projectDir := t.TempDir()
+		if err := os.Symlink(outside, filepath.Join(projectDir, "shared")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(projectDir, "secret.txt")); err != nil {
+			t.Fatal(err)
+		}
+		chdir(t, projectDir)
+		_, err := runProject(t, "", templateDir)
+		assert.ErrorContains(t, err, "outside of the project", action)
+	}
+	for name, expected := range map[string]string{"secret.txt": "secret\n", "config.yaml": "port: 1\n", "keep.txt": "keep\n"} {
+		content, err := os.ReadFile(filepath.Join(outside, name))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content), name)
+	}
+	assert.NoFileExists(t, filepath.Join(outside, "app.env"))
+	info, err := os.Stat(filepath.Join(outside, "secret.txt"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	}
+
+	// Los enlaces dentro del proyecto se siguen
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{"actions": [{"type": "appendFile", "path": "alias.txt", "content": "b\n"}]}`,
+		"a.txt":            "a\n",
+	})
+	if err := os.Symlink("a.txt", filepath.Join(templateDir, "alias.txt")); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "", templateDir)
+	assert.NoError(t, err)
+	content, _ := os.ReadFile("a.txt")
+	assert.Equal(t, "a\nb\n", string(content))
+}
+
+func TestProjectGitInitWithMock(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "prompts": [{"name": "name", "description": "Project name:"}],
+  "git": {"init": true, "branch": "develop", "message": "feat: scaffold {{.Inputs.name}}", "remote": "git@github.com:acme/{{.Inputs.name}}.git"}
+}`,
+		"README.md": "readme\n",
+	})
+	projectPath := t.TempDir()
+	chdir(t, projectPath)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := mgit.NewMockCmd(t)
+	cmd.EXPECT().Init(cwd, "develop").Return(nil)
+	cmd.EXPECT().AddAll(cwd).Return(nil)
+	cmd.EXPECT().Commit(cwd, "feat: scaffold svc").Return(nil)
+	cmd.EXPECT().AddRemote(cwd, "origin", "git@github.com:acme/svc.git").Return(nil)
+	projectCmd := project.NewProjectCommand(cmd)
+	projectCmd.SetArgs([]string{templateDir})
+	projectCmd.SetIn(strings.NewReader("svc\n"))
+	projectCmd.SetOut(new(bytes.Buffer))
+	if err := projectCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProjectGitInitFlags(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "kli")
+	t.Setenv("GIT_AUTHOR_EMAIL", "kli@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "kli")
+	t.Setenv("GIT_COMMITTER_EMAIL", "kli@example.com")
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "my-service\n", templateDir, "--git", "--git-branch", "trunk", "--git-remote", "https://example.com/my-service.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("trunk", gitRun(t, ".", "branch", "--show-current"))
+	assert.Equal("chore: initial commit", gitRun(t, ".", "log", "-1", "--pretty=%s"))
+	assert.Equal("https://example.com/my-service.git", gitRun(t, ".", "remote", "get-url", "origin"))
+	assert.Empty(gitRun(t, ".", "status", "--porcelain"))
+
+	templateDir = t.TempDir()
+	writeFiles(t, templateDir, map[string]string{".kliproject.json": `{"git": {"init": true}}`})
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "", templateDir, "--git=false")
+	assert.NoError(err)
+	assert.NoDirExists(".git")
+}
+
+func TestTemplateValidate(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+	output, err := runTemplate(t, "validate", templateDir)
+	assert.NoError(err)
+	assert.Contains(output, "is valid")
+
+	templateDir = t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": `{
+  "$schema": "https://raw.githubusercontent.com/KaribuLab/kli/main/schema/kliproject.schema.json",
+  "prompts": [{"name": "name"}, {"name": "name"}],
+  "render": ["*.go"],
+  "templates": [{"rootDir": "templates", "files": [
+    {"source": "templates/missing.tmpl", "destination": "a.txt"},
+    {"source": "templates/README.md.tmpl", "destination": "{{.Inputs.folder}}/README.md"}
+  ]}],
+  "posthooks": [{"name": "hook", "command": "echo {{.Inputs.name}}", "timeout": "soon"}],
+  "actions": [{"type": "explode"}]
+}`,
+		"templates/README.md.tmpl": "# {{.Inputs.title}} {{range .Inputs.items}}{{.Inputs.ignored}}{{end}}{{$.Inputs.owner}} {{index .Inputs \"year\"}}\n",
+		"main.go":                  "package {{.Inputs.name\n",
+		"{{.Inputs.dir}}/x.txt":    "x\n",
+	})
+	output, err = runTemplate(t, "validate", templateDir)
+	assert.ErrorContains(err, "has 11 problems")
+	for _, expected := range []string{
+		"templates[0].files[0]: source templates/missing.tmpl not found",
+		"main.go: template: main.go:2: unclosed action",
+		"posthooks[0]: invalid timeout \"soon\"",
+		"actions[0]: unknown action type \"explode\"",
+		"prompts[1]: input name is declared more than once",
+		"input dir is not declared in prompts (used in {{.Inputs.dir}})",
+		"input folder is not declared in prompts (used in templates[0].files[1].destination)",
+		"input items is not declared in prompts (used in templates/README.md.tmpl)",
+		"input owner is not declared in prompts (used in templates/README.md.tmpl)",
+		"input title is not declared in prompts (used in templates/README.md.tmpl)",
+		"input year is not declared in prompts (used in templates/README.md.tmpl)",
+	} {
+		assert.Contains(output, expected)
+	}
+	assert.NotContains(output, "ignored")
+}
+
+func TestTemplateValidateReportsUnknownFields(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": "{\n  \"prompts\": [],\n  \"tempaltes\": []\n}",
+	})
+	// Sin SilenceUsage en el comando raíz, como lo ejecuta kli
+	templateCmd := project.NewTemplateCommand(git.NewGitCmd())
+	templateCmd.SetArgs([]string{"validate", templateDir})
+	output := new(bytes.Buffer)
+	templateCmd.SetOut(output)
+	templateCmd.SetErr(output)
+	err := templateCmd.Execute()
+	assert.ErrorContains(err, "1 problems")
+	assert.Contains(output.String(), `line 3, column 3: json: unknown field "tempaltes"`)
+	assert.NotContains(output.String(), "Usage:")
+}
+
+func TestProjectConfigIsStrict(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": "{\n  \"prompts\": [],\n  \"prehooks\": []\n}",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", templateDir)
+	assert.ErrorContains(t, err, `error reading .kliproject.json: line 3, column 3: json: unknown field "prehooks"`)
+
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": "{\n  \"posthooks\": [{\"name\": \"a\", \"command\": \"true\", \"env\": {\"label\": \"x\"}}],\n  \"prompts\": [\n    {\"name\": \"name\", \"label\": \"Name:\"}\n  ]\n}",
+	})
+	_, err = runProject(t, "", templateDir)
+	assert.ErrorContains(t, err, `line 4, column 22: json: unknown field "label"`)
+
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.json": "{\n  \"prompts\": [],\n  \"render\": \"*.go\"\n}",
+	})
+	_, err = runProject(t, "", templateDir)
+	assert.ErrorContains(t, err, "line 3, column 19")
+}
+
+func TestProjectConfigSchemaCoversConfig(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("..", "schema", "kliproject.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(payload, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"$schema", "description", "prompts", "templates", "initialisms", "render", "delimiters", "overrides", "partials", "posthooks", "hooks", "actions", "git"} {
+		assert.Contains(t, schema.Properties, field)
+	}
+}
+
+func TestProjectYAMLAndTOMLConfig(t *testing.T) {
+	configs := map[string]string{
+		".kliproject.yaml": `# Plantilla de ejemplo
+description: |
+  Servicio de ejemplo
+  en varias líneas
+prompts:
+  - name: name
+    description: "Project name:"
+    type: string
+templates:
+  - rootDir: templates
+    delete: true
+    files:
+      - source: templates/README.md.tmpl
+        destination: README.md
+`,
+		".kliproject.toml": `# Plantilla de ejemplo
+description = """
+Servicio de ejemplo
+en varias líneas
+"""
+
+[[prompts]]
+name = "name"
+description = "Project name:"
+type = 'string'
+
+[[templates]]
+rootDir = "templates"
+delete = true
+files = [
+  { source = "templates/README.md.tmpl", destination = "README.md" },
+]
+`,
+	}
+	for name, config := range configs {
+		templateDir := t.TempDir()
+		files := map[string]string{}
+		for file, content := range sampleTemplate {
+			if file != ".kliproject.json" {
+				files[file] = content
+			}
+		}
+		files[name] = config
+		writeFiles(t, templateDir, files)
+		chdir(t, t.TempDir())
+		_, err := runProject(t, "my-service\n", templateDir)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		assertSampleProject(t, ".")
+		assert.NoFileExists(t, name)
+	}
+}
+
+func TestProjectTOMLConfigTables(t *testing.T) {
+	assert := assert.New(t)
+	config := `[[hooks.postcopy]]
+name = "first"
+command = """
+echo $VALUE >> order.txt
+"""
+[hooks.postcopy.env]
+VALUE = "first"
+
+[[hooks.postcopy]]
+name = "second"
+command = "echo $VALUE >> order.txt"
+[hooks.postcopy.env]
+VALUE = "second"
+`
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, map[string]string{
+		".kliproject.toml": strings.ReplaceAll(config, "\n", "\r\n"),
+		"README.md":        "readme\n",
+	})
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "", templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile("order.txt")
+	assert.NoError(err)
+	assert.Equal("first\nsecond\n", string(content))
+}
+
+func TestProjectConfigFormatErrors(t *testing.T) {
+	cases := []struct {
+		files    map[string]string
+		expected string
+	}{
+		{
+			map[string]string{".kliproject.json": "{}", ".kliproject.yml": "{}"},
+			"more than one config file: .kliproject.json, .kliproject.yml",
+		},
+		{
+			map[string]string{".kliproject.yaml": "prompt:\n  - name: a\n"},
+			`error reading .kliproject.yaml: json: unknown field "prompt"`,
+		},
+		{
+			map[string]string{".kliproject.yaml": "description: a\nprompts: 1\n"},
+			"error reading .kliproject.yaml: json: cannot unmarshal number into Go struct field",
+		},
+		{
+			map[string]string{".kliproject.toml": "description = \"a\"\n\n[[prompts]]\nname = \"a\nb\"\n"},
+			"error reading .kliproject.toml: toml: line 4",
+		},
+		{
+			map[string]string{".kliproject.toml": "description = \"a\"\ndescription = \"b\"\n"},
+			"Key 'description' has already been defined",
+		},
+		{
+			map[string]string{"README.md": "readme\n"},
+			".kliproject.json, .kliproject.yaml, .kliproject.yml, .kliproject.toml not found",
+		},
+	}
+	for _, c := range cases {
+		templateDir := t.TempDir()
+		writeFiles(t, templateDir, c.files)
+		chdir(t, t.TempDir())
+		_, err := runProject(t, "", templateDir)
+		assert.ErrorContains(t, err, c.expected)
+	}
+}
+
+func TestTemplateTest(t *testing.T) {
+	assert := assert.New(t)
+	templateDir := t.TempDir()
+	writeFiles(t, templateDir, sampleTemplate)
+	writeFiles(t, templateDir, map[string]string{
+		".kli/fixtures/basic.json":  `{"name": "my-service"}`,
+		".kli/fixtures/other.yaml":  "name: other-app\n",
+		".kli/fixtures/broken.json": `{}`,
+	})
+	output, err := runTemplate(t, "test", templateDir, "--update")
+	assert.ErrorContains(err, "1 of 3 fixtures failed")
+	assert.Contains(output, "FAIL broken\n    fixture does not answer prompt name")
+	assert.Contains(output, "updated basic")
+	assert.Contains(output, "updated other")
+	assert.NoError(os.Remove(filepath.Join(templateDir, ".kli", "fixtures", "broken.json")))
+	assertSampleProject(t, filepath.Join(templateDir, ".kli", "golden", "basic"))
+	assert.NoDirExists(filepath.Join(templateDir, ".kli", "golden", "basic", ".kli"))
+
+	output, err = runTemplate(t, "test", templateDir)
+	assert.NoError(err)
+	assert.Contains(output, "ok   basic")
+	assert.Contains(output, "ok   other")
+
+	writeFiles(t, templateDir, map[string]string{
+		"templates/README.md.tmpl":    "# {{toPascalCase .Inputs.name}}!\n",
+		".kli/golden/basic/extra.txt": "extra\n",
+	})
+	assert.NoError(os.Remove(filepath.Join(templateDir, ".kli", "golden", "other", "other_app.txt")))
+	output, err = runTemplate(t, "test", templateDir)
+	assert.ErrorContains(err, "2 of 2 fixtures failed")
+	assert.Contains(output, "FAIL basic")
+	assert.Contains(output, "--- golden/README.md\n+++ output/README.md\n")
+	assert.Contains(output, "-# MyService\n+# MyService!\n")
+	assert.Contains(output, "missing file extra.txt")
+	assert.Contains(output, "unexpected file other_app.txt")
+
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "my-service\n", templateDir)
+	assert.NoError(err)
+	assert.NoDirExists(".kli")
+}
+
+func TestTemplateInit(t *testing.T) {
+	assert := assert.New(t)
+	projectDir := filepath.Join(t.TempDir(), "my-service")
+	writeFiles(t, projectDir, map[string]string{
+		"go.mod":                            "module github.com/acme/my-service\n",
+		"cmd/my-service/main.go":            "package main\n\n// MyService starts myService\nconst name = \"MY_SERVICE\"\n",
+		"internal/my_service/my_service.go": "package myservice\n",
+		".github/workflows/ci.yml":          "name: My Service\nrun: echo ${{ github.sha }}\n",
+		"static.txt":                        "unchanged {{ value }}\n",
+		"logo.bin":                          "\x00my-service",
+		".gitignore":                        "bin/\n",
+		"bin/my-service":                    "binary",
+		".git/config":                       "[core]\n",
+	})
+	templateDir := filepath.Join(t.TempDir(), "template")
+	output, err := runTemplate(t, "init", projectDir, templateDir, "--replace", "my-service=name")
+	assert.NoError(err)
+	assert.Contains(output, "Renamed cmd/my-service to cmd/{{toKebabCase .Inputs.name}}")
+	assert.Contains(output, "Templated .github/workflows/ci.yml")
+	assert.NoDirExists(filepath.Join(templateDir, "bin"))
+	assert.NoDirExists(filepath.Join(templateDir, ".git"))
+
+	config, err := os.ReadFile(filepath.Join(templateDir, ".kliproject.json"))
+	assert.NoError(err)
+	assert.Contains(string(config), `"description": "Template generated from my-service"`)
+	assert.Contains(string(config), `"name": "name"`)
+	assert.Contains(string(config), `"/.github/workflows/ci.yml"`)
+	assert.Contains(string(config), `"left": "[["`)
+	assert.NotContains(string(config), "static.txt")
+	_, err = runTemplate(t, "validate", templateDir)
+	assert.NoError(err)
+
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "order-service\n", templateDir)
+	assert.NoError(err)
+	for name, expected := range map[string]string{
+		"go.mod":                    "module github.com/acme/order-service\n",
+		"cmd/order-service/main.go": "package main\n\n// OrderService starts orderService\nconst name = \"ORDER_SERVICE\"\n",
+		"internal/order_service/order_service.go": "package orderservice\n",
+		".github/workflows/ci.yml":                "name: Order Service\nrun: echo ${{ github.sha }}\n",
+		"static.txt":                              "unchanged {{ value }}\n",
+		"logo.bin":                                "\x00my-service",
+	} {
+		content, err := os.ReadFile(name)
+		assert.NoError(err)
+		assert.Equal(expected, string(content), name)
+	}
+
+	_, err = runTemplate(t, "init", projectDir, templateDir, "--replace", "my-service=name")
+	assert.ErrorContains(err, "already exists and is not empty")
+	_, err = runTemplate(t, "init", projectDir, filepath.Join(projectDir, "template"), "--replace", "my-service=name")
+	assert.ErrorContains(err, "cannot be created inside")
+	_, err = runTemplate(t, "init", projectDir, t.TempDir(), "--replace", "my-service=project-name")
+	assert.ErrorContains(err, `invalid input name "project-name"`)
+}
+
+func TestTemplateInitAsksLiterals(t *testing.T) {
+	assert := assert.New(t)
+	projectDir := t.TempDir()
+	writeFiles(t, projectDir, map[string]string{
+		"README.md": "# acme-api by Acme Corp\n",
+	})
+	templateDir := filepath.Join(t.TempDir(), "template")
+	templateCmd := project.NewTemplateCommand(git.NewGitCmd())
+	templateCmd.SetArgs([]string{"init", projectDir, templateDir})
+	templateCmd.SetIn(strings.NewReader("acme-api\nname\nProject name:\nAcme Corp\ncompany\n\n\n"))
+	output := new(bytes.Buffer)
+	templateCmd.SetOut(output)
+	templateCmd.SetErr(output)
+	assert.NoError(templateCmd.Execute())
+	assert.Contains(output.String(), `Input name for "Acme Corp":`)
+
+	config, err := os.ReadFile(filepath.Join(templateDir, ".kliproject.json"))
+	assert.NoError(err)
+	assert.Contains(string(config), `"description": "Project name:"`)
+	assert.Contains(string(config), `"description": "Company:"`)
+	readme, err := os.ReadFile(filepath.Join(templateDir, "README.md"))
+	assert.NoError(err)
+	assert.Equal("# {{toKebabCase .Inputs.name}} by {{toTitleCase .Inputs.company}}\n", string(readme))
+}
+
+func TestTemplateInitReplacesWholeWords(t *testing.T) {
+	assert := assert.New(t)
+	projectDir := t.TempDir()
+	writeFiles(t, projectDir, map[string]string{
+		"main.go": "// api: rapid capital\nclient := newAPIClient(api_url)\n",
+	})
+	templateDir := filepath.Join(t.TempDir(), "template")
+	_, err := runTemplate(t, "init", projectDir, templateDir, "--replace", "api=service")
+	assert.NoError(err)
+	content, err := os.ReadFile(filepath.Join(templateDir, "main.go"))
+	assert.NoError(err)
+	assert.Equal("// {{toKebabCase .Inputs.service}}: rapid capital\nclient := new{{toPascalCase .Inputs.service}}Client({{toKebabCase .Inputs.service}}_url)\n", string(content))
+}
+
+var layeredTemplates = map[string]string{
+	"base/.kliproject.json":     `{"prompts": [{"name": "name", "description": "Name:"}], "render": ["go.mod"]}`,
+	"base/go.mod":               "module {{.Inputs.name}}\n",
+	"base/main.go":              "package main // base\n",
+	"base/README.md":            "# base\n",
+	"base/.gitignore":           "bin/",
+	"postgres/.kliproject.json": `{"prompts": [{"name": "name", "description": "Name:"}, {"name": "dbName", "description": "Database:"}], "render": ["*.sql"], "actions": [{"type": "appendFile", "path": "README.md", "content": "\n## Postgres ([[.Inputs.dbName]])\n"}], "delimiters": {"left": "[[", "right": "]]"}}`,
+	"postgres/db/schema.sql":    "CREATE DATABASE [[.Inputs.dbName]];\n",
+	"postgres/.gitignore":       "data/\n",
+	"kafka/.kliproject.json":    `{"prompts": []}`,
+	"kafka/README.md":           "# kafka\n",
+	"kafka/kafka.yml":           "topics: []\n",
+	"variant/.kliproject.json":  `{"prompts": [{"name": "name", "description": "Service name:"}], "render": ["config.txt"], "layers": [{"source": "../base"}, {"source": "../postgres", "conflict": "append"}, {"source": "../kafka", "conflict": "skip"}]}`,
+	"variant/main.go":           "package main // variant\n",
+	"variant/config.txt":        "db={{.Inputs.dbName}}\n",
+}
+
+func assertLayeredProject(t *testing.T, dir string) {
+	t.Helper()
+	assert := assert.New(t)
+	for name, expected := range map[string]string{
+		"go.mod":        "module svc\n",
+		"main.go":       "package main // variant\n",
+		"README.md":     "# base\n\n## Postgres (appdb)\n",
+		".gitignore":    "bin/\ndata/\n",
+		"db/schema.sql": "CREATE DATABASE appdb;\n",
+		"kafka.yml":     "topics: []\n",
+		"config.txt":    "db=appdb\n",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		assert.NoError(err)
+		assert.Equal(expected, string(content), name)
+	}
+	assert.NoFileExists(filepath.Join(dir, ".kliproject.json"))
+}
+
+func TestProjectLayers(t *testing.T) {
+	assert := assert.New(t)
+	templatesDir := t.TempDir()
+	writeFiles(t, templatesDir, layeredTemplates)
+
+	chdir(t, t.TempDir())
+	_, err := runProject(t, "svc\nappdb\n", filepath.Join(templatesDir, "variant"))
+	assert.NoError(err)
+	assertLayeredProject(t, ".")
+
+	// Las rutas relativas de un archivo o repositorio son subdirectorios del
+	// mismo origen
+	archivePath := filepath.Join(t.TempDir(), "templates.tar.gz")
+	writeTarGz(t, archivePath, "templates-v1.0.0", layeredTemplates)
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "svc\nappdb\n", archivePath+"//variant")
+	assert.NoError(err)
+	assertLayeredProject(t, ".")
+
+	_, err = runTemplate(t, "validate", filepath.Join(templatesDir, "variant"))
+	assert.NoError(err)
+
+	writeFiles(t, templatesDir, map[string]string{
+		"failing/.kliproject.json": `{"prompts": [], "layers": [{"source": "../base"}, {"source": "../kafka", "conflict": "fail"}]}`,
+		"self/.kliproject.json":    `{"prompts": [], "layers": [{"source": "../self"}]}`,
+		"unknown/.kliproject.json": `{"prompts": [], "layers": [{"source": "../base", "conflict": "merge"}]}`,
+	})
+	chdir(t, t.TempDir())
+	_, err = runProject(t, "svc\n", filepath.Join(templatesDir, "failing"))
+	assert.ErrorContains(err, "file README.md already exists")
+	_, err = runProject(t, "", filepath.Join(templatesDir, "self"))
+	assert.ErrorContains(err, "includes itself")
+	_, err = runProject(t, "", filepath.Join(templatesDir, "unknown"))
+	assert.ErrorContains(err, `unknown conflict strategy "merge"`)
+	output, err := runTemplate(t, "validate", filepath.Join(templatesDir, "unknown"))
+	assert.Error(err)
+	assert.Contains(output, `layers[0]: unknown conflict strategy "merge"`)
+}
+
+func runGenerate(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	generateCmd := project.NewGenerateCommand(git.NewGitCmd())
+	generateCmd.SetArgs(args)
+	generateCmd.SetIn(strings.NewReader(stdin))
+	output := new(bytes.Buffer)
+	generateCmd.SetOut(output)
+	generateCmd.SetErr(output)
+	generateCmd.SilenceUsage = true
+	err := generateCmd.Execute()
+	return output.String(), err
+}
+
+const handlerGenerator = `{
+  "prompts": [{"name": "name", "description": "Handler name:"}],
+  "render": ["internal/**"],
+  "actions": [
+    {"type": "insert", "path": "main.go", "anchor": "// kli:routes", "content": "mux.HandleFunc(\"/{{toKebabCase .Inputs.name}}\", handlers.{{toPascalCase .Inputs.name}})"},
+    {"type": "insert", "path": "main.go", "anchor": "import (", "position": "after", "content": "\t\"example.com/app/internal/handlers\"", "when": "{{eq .Inputs.name \"first\"}}"}
+  ]
+}`
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	projectDir := t.TempDir()
+	writeFiles(t, projectDir, map[string]string{
+		"main.go": "package main\n\nimport (\n)\n\nfunc routes() {\n\t// kli:routes\n}\n",
+		".kligenerators/handler/.kliproject.json":                                  handlerGenerator,
+		".kligenerators/handler/internal/handlers/{{toSnakeCase .Inputs.name}}.go": "package handlers\n\n// {{toPascalCase .Inputs.name}} handles /{{toKebabCase .Inputs.name}}\n",
+	})
+	chdir(t, projectDir)
+	output, err := runGenerate(t, "first\n", "handler")
+	assert.NoError(err)
+	assert.Contains(output, "Running action insert")
+	_, err = runGenerate(t, "user profile\n", "handler")
+	assert.NoError(err)
+	// Volver a generar un componente no duplica los fragmentos insertados
+	_, err = runGenerate(t, "user profile\n", "handler", "--conflict", "skip")
+	assert.NoError(err)
+
+	content, err := os.ReadFile("main.go")
+	assert.NoError(err)
+	assert.Equal("package main\n\nimport (\n\t\"example.com/app/internal/handlers\"\n)\n\nfunc routes() {\n\tmux.HandleFunc(\"/first\", handlers.First)\n\tmux.HandleFunc(\"/user-profile\", handlers.UserProfile)\n\t// kli:routes\n}\n", string(content))
+	content, err = os.ReadFile(filepath.Join("internal", "handlers", "user_profile.go"))
+	assert.NoError(err)
+	assert.Equal("package handlers\n\n// UserProfile handles /user-profile\n", string(content))
+	assert.FileExists(filepath.Join("internal", "handlers", "first.go"))
+	assert.NoFileExists(".kliproject.json")
+	assert.NoFileExists(".kliproject.lock.json")
+
+	writeFiles(t, projectDir, map[string]string{"internal/handlers/user_profile.go": "package handlers\n"})
+	_, err = runGenerate(t, "user profile\n", "handler")
+	assert.ErrorContains(err, "internal/handlers/user_profile.go")
+
+	writeFiles(t, projectDir, map[string]string{"main.go": "package main\n"})
+	_, err = runGenerate(t, "other\n", "handler")
+	assert.ErrorContains(err, `anchor "// kli:routes" not found in main.go`)
+
+	_, err = runGenerate(t, "", "handler", "-w", "missing")
+	assert.ErrorContains(err, "project directory missing not found")
+}
+
+func TestGenerateFromSubdirectory(t *testing.T) {
+	assert := assert.New(t)
+	projectDir := t.TempDir()
+	writeFiles(t, projectDir, map[string]string{
+		"main.go":               "package main\n\nimport (\n)\n\nfunc routes() {\n\t// kli:routes\n}\n",
+		"internal/app/app.go":   "package app\n",
+		".kliproject.lock.json": "{}\n",
+		".kligenerators/handler/.kliproject.json":                                  handlerGenerator,
+		".kligenerators/handler/internal/handlers/{{toSnakeCase .Inputs.name}}.go": "package handlers\n",
+	})
+	chdir(t, filepath.Join(projectDir, "internal", "app"))
+	_, err := runGenerate(t, "orders\n", "handler")
+	assert.NoError(err)
+	assert.FileExists(filepath.Join(projectDir, "internal", "handlers", "orders.go"))
+	assert.NoDirExists("internal")
+	content, err := os.ReadFile(filepath.Join(projectDir, "main.go"))
+	assert.NoError(err)
+	assert.Contains(string(content), `mux.HandleFunc("/orders", handlers.Orders)`)
+}
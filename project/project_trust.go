@@ -0,0 +1,130 @@
+package project
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/KaribuLab/kli/config"
+)
+
+// normalizeSource reduce una URL de repositorio a host/ruta para comparar
+// orígenes sin importar el protocolo: https://github.com/acme/tpl.git y
+// git@github.com:acme/tpl quedan ambos como github.com/acme/tpl.
+func normalizeSource(source string) string {
+	source = strings.TrimSpace(source)
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://"} {
+		source = strings.TrimPrefix(source, prefix)
+	}
+	if at := strings.Index(source, "@"); at >= 0 && at < strings.IndexAny(source+"/", ":/") {
+		source = source[at+1:]
+	}
+	if colon := strings.Index(source, ":"); colon >= 0 && !strings.Contains(source[:colon], "/") {
+		source = source[:colon] + "/" + source[colon+1:]
+	}
+	source = strings.TrimSuffix(strings.TrimSuffix(source, "/"), ".git")
+	return strings.ToLower(source)
+}
+
+// isTrustedSource indica si source coincide con alguna entrada de trusted. Una
+// entrada confía en el origen exacto y en todo lo que está debajo de él, por
+// ejemplo github.com/acme confía en github.com/acme/go-service.
+func isTrustedSource(trusted []string, source string) bool {
+	source = normalizeSource(source)
+	for _, entry := range trusted {
+		entry = normalizeSource(entry)
+		if entry == "" {
+			continue
+		}
+		if source == entry || strings.HasPrefix(source, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalDirectory indica si la plantilla es un directorio de la máquina del
+// usuario, cuyos hooks se consideran confiables
+func isLocalDirectory(ref templateRef) bool {
+	if isHTTPURL(ref.Location) {
+		return false
+	}
+	info, err := os.Stat(ref.Location)
+	return err == nil && info.IsDir()
+}
+
+// hookOptions son las opciones de la línea de comandos sobre los hooks
+type hookOptions struct {
+	NoHooks bool
+	Yes     bool
+}
+
+// confirmHooks decide si se ejecutan los hooks de la plantilla. Los hooks de
+// orígenes que no son directorios locales ni están en trustedSources solo se
+// ejecutan si el usuario los confirma o usa --yes.
+func confirmHooks(projectConfig projectConfig, ref templateRef, options hookOptions, reader *bufio.Reader, out io.Writer) (bool, error) {
+	stages := []struct {
+		name  string
+		hooks []projectHook
+	}{
+		{"preprompt", projectConfig.Hooks.Preprompt},
+		{"prerender", projectConfig.Hooks.Prerender},
+		{"postrender", projectConfig.Hooks.Postrender},
+		{"postcopy", projectConfig.postcopyHooks()},
+	}
+	count := 0
+	for _, stage := range stages {
+		count += len(stage.hooks)
+	}
+	if count == 0 {
+		return false, nil
+	}
+	if options.NoHooks {
+		fmt.Fprintf(out, "Skipping %d hooks\n", count)
+		return false, nil
+	}
+	if options.Yes || isLocalDirectory(ref) {
+		return true, nil
+	}
+	userConfig, err := config.Load()
+	if err != nil {
+		return false, err
+	}
+	if isTrustedSource(userConfig.TrustedSources, ref.Location) {
+		return true, nil
+	}
+	fmt.Fprintf(out, "The template %s wants to run the following hooks:\n", ref)
+	for _, stage := range stages {
+		for _, hook := range stage.hooks {
+			command := hook.Command
+			if len(hook.Args) > 0 {
+				command = strings.Join(hook.Args, " ")
+			}
+			fmt.Fprintf(out, "  %s %s: %s\n", stage.name, hook.Name, command)
+		}
+	}
+	fmt.Fprint(out, "Run these hooks? [y]es, [n]o, [a]lways trust this source: ")
+	answer, err := reader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || answer == "") {
+		if errors.Is(err, io.EOF) {
+			return false, fmt.Errorf("hooks from %s require confirmation, use --yes to run them or --no-hooks to skip them", ref.Location)
+		}
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	case "a", "always":
+		userConfig.TrustedSources = append(userConfig.TrustedSources, normalizeSource(ref.Location))
+		err = config.Save(userConfig)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	fmt.Fprintf(out, "Skipping %d hooks\n", count)
+	return false, nil
+}
@@ -0,0 +1,490 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KaribuLab/kli/git"
+	"github.com/spf13/cobra"
+)
+
+// updateSummary agrupa las rutas afectadas por `kli project update`
+type updateSummary struct {
+	Added     []string
+	Updated   []string
+	Merged    []string
+	Removed   []string
+	Kept      []string
+	Conflicts []string
+}
+
+func (s updateSummary) Print(out io.Writer) {
+	groups := []struct {
+		label string
+		paths []string
+	}{
+		{"added", s.Added},
+		{"updated", s.Updated},
+		{"merged", s.Merged},
+		{"removed", s.Removed},
+		{"kept", s.Kept},
+		{"conflict", s.Conflicts},
+	}
+	for _, group := range groups {
+		for _, p := range group.paths {
+			fmt.Fprintf(out, "%-9s %s\n", group.label, p)
+		}
+	}
+	if len(s.Conflicts) > 0 {
+		fmt.Fprintf(out, "%d files have conflicts, resolve the conflict markers before committing\n", len(s.Conflicts))
+	}
+}
+
+// listFiles retorna las rutas relativas de los archivos bajo dir
+func listFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	if dir == "" {
+		return files, nil
+	}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+func sameContent(a, b string) bool {
+	contentA, errA := os.ReadFile(a)
+	contentB, errB := os.ReadFile(b)
+	return errA == nil && errB == nil && bytes.Equal(contentA, contentB)
+}
+
+func installFile(src, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return copyFile(src, dst)
+}
+
+// mergeProject aplica sobre projectPath los cambios entre basePath (la versión
+// de la plantilla con la que se generó el proyecto) y newPath (la versión
+// nueva), conservando las modificaciones locales. Cuando ambos cambian el
+// mismo archivo se hace un merge de tres vías con git y los conflictos quedan
+// marcados en el archivo. Un basePath vacío indica que no hay versión base.
+func mergeProject(gitCmd git.Cmd, basePath, newPath, projectPath string) (updateSummary, error) {
+	var summary updateSummary
+	baseFiles, err := listFiles(basePath)
+	if err != nil {
+		return summary, err
+	}
+	newFiles, err := listFiles(newPath)
+	if err != nil {
+		return summary, err
+	}
+	var paths []string
+	for p := range baseFiles {
+		paths = append(paths, p)
+	}
+	for p := range newFiles {
+		if !baseFiles[p] {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	emptyFile, err := os.CreateTemp("", "kli_empty_*")
+	if err != nil {
+		return summary, err
+	}
+	emptyFile.Close()
+	defer os.Remove(emptyFile.Name())
+	for _, rel := range paths {
+		if rel == projectLockFileName {
+			continue
+		}
+		baseFile := filepath.Join(basePath, filepath.FromSlash(rel))
+		newFile := filepath.Join(newPath, filepath.FromSlash(rel))
+		currentFile := filepath.Join(projectPath, filepath.FromSlash(rel))
+		_, statErr := os.Stat(currentFile)
+		currentExists := statErr == nil
+		inBase := baseFiles[rel]
+		switch {
+		case !newFiles[rel]:
+			if !currentExists {
+				continue
+			}
+			if sameContent(currentFile, baseFile) {
+				err = os.Remove(currentFile)
+				if err != nil {
+					return summary, err
+				}
+				summary.Removed = append(summary.Removed, rel)
+			} else {
+				summary.Kept = append(summary.Kept, rel)
+			}
+		case !currentExists:
+			if inBase {
+				summary.Kept = append(summary.Kept, rel)
+				continue
+			}
+			err = installFile(newFile, currentFile)
+			if err != nil {
+				return summary, err
+			}
+			summary.Added = append(summary.Added, rel)
+		case sameContent(currentFile, newFile):
+			continue
+		case inBase && sameContent(baseFile, newFile):
+			continue
+		case inBase && sameContent(currentFile, baseFile):
+			err = installFile(newFile, currentFile)
+			if err != nil {
+				return summary, err
+			}
+			summary.Updated = append(summary.Updated, rel)
+		default:
+			if isBinaryFile(currentFile) || isBinaryFile(newFile) {
+				err = installFile(newFile, currentFile+".kli-new")
+				if err != nil {
+					return summary, err
+				}
+				summary.Conflicts = append(summary.Conflicts, rel)
+				continue
+			}
+			if !inBase {
+				baseFile = emptyFile.Name()
+			}
+			conflict, err := gitCmd.MergeFile(currentFile, baseFile, newFile)
+			if err != nil {
+				return summary, err
+			}
+			if conflict {
+				summary.Conflicts = append(summary.Conflicts, rel)
+			} else {
+				summary.Merged = append(summary.Merged, rel)
+			}
+		}
+	}
+	return summary, nil
+}
+
+// templateVersion es una versión de la plantilla que se renderiza durante la
+// actualización
+type templateVersion struct {
+	path   string
+	commit string
+	config projectConfig
+	layers []*loadedLayer
+}
+
+func loadTemplateVersion(gitCmd git.Cmd, loader *layerLoader, ref templateRef, branch string, dst string) (*templateVersion, error) {
+	loaded, err := loadTemplate(gitCmd, ref, branch, loader.options, dst, loader.stderr)
+	if err != nil {
+		return nil, err
+	}
+	projectConfig, err := loadProjectConfig(loaded.Path)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := loader.load(ref, branch, projectConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &templateVersion{path: loaded.Path, commit: loaded.Commit, config: projectConfig, layers: layers}, nil
+}
+
+// stagingHooks retorna solo los hooks que se ejecutan en el directorio
+// temporal de la plantilla. Los hooks postcopy no se ejecutan al actualizar
+// porque el proyecto ya existe.
+func stagingHooks(hooks lifecycleHooks) lifecycleHooks {
+	return lifecycleHooks{Preprompt: hooks.Preprompt, Prerender: hooks.Prerender, Postrender: hooks.Postrender}
+}
+
+// render genera la versión con las respuestas de prompt siguiendo los mismos
+// pasos que `kli project`: los hooks previos y posteriores al renderizado,
+// las capas y las acciones. Las acciones gitInit se omiten porque el proyecto
+// ya tiene su repositorio.
+func (v *templateVersion) render(prompt projectPrompt, runHooks bool, gitCmd git.Cmd, cmd *cobra.Command) error {
+	hooks := stagingHooks(v.config.Hooks)
+	if !runHooks {
+		hooks = lifecycleHooks{}
+	}
+	stageRenderer, err := newRenderer(v.path, v.config, prompt)
+	if err != nil {
+		return err
+	}
+	stageHooks := hookRunner{
+		dir:      v.path,
+		renderer: stageRenderer,
+		stdout:   cmd.OutOrStdout(),
+		stderr:   cmd.ErrOrStderr(),
+	}
+	stageHooks.stage = "preprompt"
+	err = stageHooks.runHooks(hooks.Preprompt)
+	if err != nil {
+		return err
+	}
+	stageHooks.stage = "prerender"
+	err = stageHooks.runHooks(hooks.Prerender)
+	if err != nil {
+		return err
+	}
+	r, err := renderProject(v.path, v.config, prompt)
+	if err != nil {
+		return err
+	}
+	err = renderLayers(v.path, v.layers, prompt)
+	if err != nil {
+		return err
+	}
+	stageHooks.stage = "postrender"
+	stageHooks.renderer = r
+	err = stageHooks.runHooks(hooks.Postrender)
+	if err != nil {
+		return err
+	}
+	actions := actionRunner{dir: v.path, gitCmd: gitCmd, stdout: io.Discard}
+	for _, layer := range v.layers {
+		actions.renderer = layer.renderer
+		err = actions.runActions(withoutGitInit(layer.config.Actions))
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", layer.ref, err)
+		}
+	}
+	actions.renderer = r
+	err = actions.runActions(withoutGitInit(v.config.Actions))
+	if err != nil {
+		return err
+	}
+	return checkSymlinks(v.path)
+}
+
+func newProjectUpdateCommand(gitCmd git.Cmd) *cobra.Command {
+	updateCommand := &cobra.Command{
+		Use:   "update",
+		Short: "Apply a newer version of the template to an existing project",
+		Long:  "Render the template version recorded in " + projectLockFileName + " and the new version with the same answers, and merge the differences into the project keeping local modifications",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workdir, err := cmd.Flags().GetString("workdir")
+			if err != nil {
+				return err
+			}
+			ref, err := cmd.Flags().GetString("ref")
+			if err != nil {
+				return err
+			}
+			offline, err := cmd.Flags().GetBool("offline")
+			if err != nil {
+				return err
+			}
+			refresh, err := cmd.Flags().GetBool("refresh")
+			if err != nil {
+				return err
+			}
+			options := fetchOptions{Offline: offline, Refresh: refresh}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			projectPath := path.Join(cwd, workdir)
+			lock, err := readProjectLock(projectPath)
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%s not found in %s, the project was not generated by kli or predates lock files", projectLockFileName, projectPath)
+			}
+			if err != nil {
+				return err
+			}
+			tempPath, err := os.MkdirTemp("", "kli_*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tempPath)
+
+			baseRef := templateRef{Location: lock.Source, Subdir: lock.Subdir, Ref: lock.Ref}
+			if lock.Commit != "" {
+				baseRef.Ref = lock.Commit
+			}
+			baseSource, err := resolveSource(gitCmd, baseRef, lock.Branch)
+			if err != nil {
+				return err
+			}
+			// Sin historial no se puede obtener la versión con la que se
+			// generó el proyecto y cada diferencia sería un conflicto
+			if _, ok := baseSource.(*gitSource); !ok {
+				return fmt.Errorf("%s is not a Git template, only templates from a Git repository can be updated", lock.Source)
+			}
+			if !cmd.Flags().Changed("ref") {
+				ref, err = defaultUpdateRef(gitCmd, lock)
+				if err != nil {
+					return err
+				}
+				if ref != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "Applying %s\n", ref)
+				}
+			}
+			loader := &layerLoader{gitCmd: gitCmd, options: options, tempPath: tempPath, stderr: cmd.ErrOrStderr()}
+			// Las capas de la base se obtienen en los commits registrados
+			loader.pinned = lock.Layers
+			base, err := loadTemplateVersion(gitCmd, loader, baseRef, lock.Branch, path.Join(tempPath, "base"))
+			loader.pinned = nil
+			if err != nil {
+				return err
+			}
+			newRef := templateRef{Location: lock.Source, Subdir: lock.Subdir, Ref: ref}
+			latest, err := loadTemplateVersion(gitCmd, loader, newRef, lock.Branch, path.Join(tempPath, "new"))
+			if err != nil {
+				return err
+			}
+
+			noHooks, err := cmd.Flags().GetBool("no-hooks")
+			if err != nil {
+				return err
+			}
+			yes, err := cmd.Flags().GetBool("yes")
+			if err != nil {
+				return err
+			}
+			// Se pide una sola confirmación para los hooks de ambas versiones
+			hooks := lifecycleHooks{
+				Preprompt:  append(append([]projectHook{}, base.config.Hooks.Preprompt...), latest.config.Hooks.Preprompt...),
+				Prerender:  append(append([]projectHook{}, base.config.Hooks.Prerender...), latest.config.Hooks.Prerender...),
+				Postrender: append(append([]projectHook{}, base.config.Hooks.Postrender...), latest.config.Hooks.Postrender...),
+			}
+			reader := bufio.NewReader(cmd.InOrStdin())
+			runHooks, err := confirmHooks(projectConfig{Hooks: stagingHooks(hooks)}, newRef, hookOptions{NoHooks: noHooks, Yes: yes}, reader, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+
+			err = base.render(projectPrompt{Inputs: copyAnswers(lock.Answers)}, runHooks, gitCmd, cmd)
+			if err != nil {
+				return err
+			}
+			inputs := copyAnswers(lock.Answers)
+			err = askPrompts(reader, layerPrompts(latest.config.Prompts, latest.layers), inputs)
+			if err != nil {
+				return err
+			}
+			err = latest.render(projectPrompt{Inputs: inputs}, runHooks, gitCmd, cmd)
+			if err != nil {
+				return err
+			}
+
+			summary, err := mergeProject(gitCmd, base.path, latest.path, projectPath)
+			if err != nil {
+				return err
+			}
+			summary.Print(cmd.OutOrStdout())
+			lock.Ref = ref
+			lock.Commit = latest.commit
+			lock.Layers = lockLayers(latest.layers)
+			lock.Answers = inputs
+			return writeProjectLock(projectPath, lock)
+		},
+	}
+	updateCommand.Flags().StringP("workdir", "w", ".", "Project directory")
+	updateCommand.Flags().String("ref", "", "Tag, branch or commit of the template to apply (default: the latest tag when the project uses a tag, otherwise the latest commit of the recorded branch)")
+	updateCommand.Flags().Bool("offline", false, "Use the cached template without accessing the network")
+	updateCommand.Flags().Bool("refresh", false, "Download the template again even if it is cached")
+	updateCommand.Flags().Bool("no-hooks", false, "Do not run the template hooks")
+	updateCommand.Flags().BoolP("yes", "y", false, "Run the template hooks without asking for confirmation")
+	return updateCommand
+}
+
+// semverTag separa un tag en el prefijo (por ejemplo v o api/v) y los números
+// de la versión. Las versiones previas como v2.0.0-rc.1 no coinciden.
+var semverTag = regexp.MustCompile(`^(.*?)(\d+)\.(\d+)\.(\d+)$`)
+
+// tagVersion retorna el prefijo y la versión de tag, o false si no es una
+// versión semántica
+func tagVersion(tag string) (string, [3]int, bool) {
+	var version [3]int
+	match := semverTag.FindStringSubmatch(tag)
+	if match == nil {
+		return "", version, false
+	}
+	for i := range version {
+		version[i], _ = strconv.Atoi(match[i+2])
+	}
+	return match[1], version, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// defaultUpdateRef retorna la versión que aplica `kli project update` sin
+// --ref. Si el proyecto se generó desde una rama se usa su último commit y si
+// se generó desde un tag se usa el tag más reciente con el mismo prefijo. Un
+// commit o un tag que no es una versión semántica requieren --ref.
+func defaultUpdateRef(gitCmd git.Cmd, lock projectLock) (string, error) {
+	if lock.Ref == "" {
+		return "", nil
+	}
+	out, err := gitCmd.Run(false, "ls-remote", "--refs", lock.Source)
+	if err != nil {
+		return "", err
+	}
+	heads := make(map[string]bool)
+	var tags []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if name, ok := strings.CutPrefix(fields[1], "refs/heads/"); ok {
+			heads[name] = true
+		} else if name, ok := strings.CutPrefix(fields[1], "refs/tags/"); ok {
+			tags = append(tags, name)
+		}
+	}
+	if heads[lock.Ref] {
+		return lock.Ref, nil
+	}
+	prefix, current, ok := tagVersion(lock.Ref)
+	if !ok {
+		return "", fmt.Errorf("the project is pinned to %s, use --ref to choose the version to apply", lock.Ref)
+	}
+	latest := lock.Ref
+	for _, tag := range tags {
+		tagPrefix, version, ok := tagVersion(tag)
+		if ok && tagPrefix == prefix && compareVersions(version, current) > 0 {
+			latest, current = tag, version
+		}
+	}
+	return latest, nil
+}
+
+func copyAnswers(answers map[string]any) map[string]any {
+	inputs := make(map[string]any, len(answers))
+	for key, value := range answers {
+		inputs[key] = value
+	}
+	return inputs
+}
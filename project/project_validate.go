@@ -0,0 +1,426 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// templateValidator revisa una plantilla sin renderizarla y acumula los
+// problemas encontrados
+type templateValidator struct {
+	templatePath string
+	config       projectConfig
+	renderer     *renderer
+	problems     []string
+	// references guarda dónde se usa cada input
+	references map[string][]string
+	// layerInputs son los prompts de las capas locales. Los de las capas
+	// remotas no se conocen sin descargarlas, por lo que si hay alguna no se
+	// reportan los inputs no declarados.
+	layerInputs  map[string]bool
+	remoteLayers bool
+}
+
+// validateTemplate retorna los problemas de la plantilla ubicada en
+// templatePath: configuración inválida, archivos inexistentes, plantillas que
+// no se pueden parsear e inputs usados que no están declarados.
+func validateTemplate(templatePath string) []string {
+	projectConfig, err := loadProjectConfig(templatePath)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	r, err := newRenderer(templatePath, projectConfig, projectPrompt{Inputs: map[string]any{}})
+	if err != nil {
+		return []string{err.Error()}
+	}
+	v := &templateValidator{
+		templatePath: templatePath,
+		config:       projectConfig,
+		renderer:     r,
+		references:   make(map[string][]string),
+		layerInputs:  make(map[string]bool),
+	}
+	v.checkPartials()
+	v.checkTemplates()
+	v.checkRender()
+	v.checkPaths()
+	v.checkHooks()
+	v.checkActions()
+	v.checkLayers()
+	v.parseText("git.message", projectConfig.Git.Message)
+	v.parseText("git.remote", projectConfig.Git.Remote)
+	v.checkInputs()
+	return v.problems
+}
+
+func (v *templateValidator) addProblem(format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// parse parsea text con los delimitadores d y registra los inputs que usa
+func (v *templateValidator) parse(where string, text string, d delimiters) {
+	tmpl, err := v.renderer.newTemplate(where, d)
+	if err != nil {
+		// Los errores de los parciales se reportan en checkPartials
+		return
+	}
+	tmpl, err = tmpl.Parse(text)
+	if err != nil {
+		v.addProblem("%s: %v", where, err)
+		return
+	}
+	if tmpl.Tree != nil {
+		v.collectInputs(where, tmpl.Tree.Root, true)
+	}
+}
+
+// parseText parsea un texto de la configuración con los delimitadores
+// globales
+func (v *templateValidator) parseText(where string, text string) {
+	if text == "" {
+		return
+	}
+	v.parse(where, text, v.renderer.delimiters)
+}
+
+func (v *templateValidator) checkPartials() {
+	for _, p := range v.renderer.partials {
+		tmpl, err := v.renderer.newTemplate("partial", p.delimiters)
+		if err != nil {
+			v.addProblem("%v", err)
+			return
+		}
+		if partial := tmpl.Lookup(p.name); partial != nil && partial.Tree != nil {
+			v.collectInputs("partial "+p.name, partial.Tree.Root, true)
+		}
+	}
+}
+
+func (v *templateValidator) checkTemplates() {
+	for i, t := range v.config.Templates {
+		for j, f := range t.Files {
+			where := fmt.Sprintf("templates[%d].files[%d]", i, j)
+			v.parseText(where+".destination", f.Destination)
+			if f.Delimiters != nil {
+				if err := validateDelimiters(*f.Delimiters); err != nil {
+					v.addProblem("%s: %v", where, err)
+					continue
+				}
+			}
+			sourcePath := filepath.Join(v.templatePath, filepath.FromSlash(f.Source))
+			if _, err := os.Stat(sourcePath); err != nil {
+				v.addProblem("%s: source %s not found", where, f.Source)
+				continue
+			}
+			if f.Raw || v.renderer.isRaw(f.Source) || isBinaryFile(sourcePath) {
+				continue
+			}
+			content, err := os.ReadFile(sourcePath)
+			if err != nil {
+				v.addProblem("%s: %v", where, err)
+				continue
+			}
+			v.parse(f.Source, string(content), v.renderer.delimitersFor(f.Source, f.Delimiters))
+		}
+	}
+}
+
+func (v *templateValidator) checkRender() {
+	if len(v.config.Render) == 0 {
+		return
+	}
+	patterns, err := compileGlobs(v.config.Render)
+	if err != nil {
+		v.addProblem("render: %v", err)
+		return
+	}
+	rules, err := loadIgnoreRules(v.templatePath)
+	if err != nil {
+		v.addProblem("%s: %v", ignoreFileName, err)
+		return
+	}
+	v.walk(func(rel string, info os.FileInfo) {
+		if !info.Mode().IsRegular() || !matchesAny(patterns, rel) || rules.Ignored(rel, false) || v.renderer.isRaw(rel) {
+			return
+		}
+		p := filepath.Join(v.templatePath, rel)
+		if isBinaryFile(p) {
+			return
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			v.addProblem("%s: %v", rel, err)
+			return
+		}
+		v.parse(filepath.ToSlash(rel), string(content), v.renderer.delimitersFor(rel, nil))
+	})
+}
+
+func (v *templateValidator) checkPaths() {
+	v.walk(func(rel string, info os.FileInfo) {
+		if isTemplatedName(info.Name(), v.renderer.delimiters) {
+			v.parse(filepath.ToSlash(rel), info.Name(), v.renderer.delimiters)
+		}
+	})
+}
+
+// walk recorre la plantilla omitiendo .git, .kli, los archivos de
+// configuración y el directorio de parciales
+func (v *templateValidator) walk(fn func(rel string, info os.FileInfo)) {
+	partials := ""
+	if v.config.Partials != "" {
+		partials = filepath.Clean(filepath.FromSlash(v.config.Partials))
+	}
+	err := filepath.Walk(v.templatePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(v.templatePath, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if info.IsDir() && (info.Name() == ".git" || rel == partials || rel == templateMetaDir) {
+			return filepath.SkipDir
+		}
+		if isTemplateConfigFile(rel) {
+			return nil
+		}
+		fn(rel, info)
+		return nil
+	})
+	if err != nil {
+		v.addProblem("%v", err)
+	}
+}
+
+func (v *templateValidator) checkHooks() {
+	stages := []struct {
+		name  string
+		hooks []projectHook
+	}{
+		{"hooks.preprompt", v.config.Hooks.Preprompt},
+		{"hooks.prerender", v.config.Hooks.Prerender},
+		{"hooks.postrender", v.config.Hooks.Postrender},
+		{"hooks.postcopy", v.config.Hooks.Postcopy},
+		{"posthooks", v.config.Posthooks},
+	}
+	for _, stage := range stages {
+		for i, hook := range stage.hooks {
+			where := fmt.Sprintf("%s[%d]", stage.name, i)
+			if (hook.Command == "") == (len(hook.Args) == 0) {
+				v.addProblem("%s: exactly one of command or args is required", where)
+			}
+			if hook.Timeout != "" {
+				if _, err := time.ParseDuration(hook.Timeout); err != nil {
+					v.addProblem("%s: invalid timeout %q", where, hook.Timeout)
+				}
+			}
+			v.parseText(where+".command", hook.Command)
+			for j, arg := range hook.Args {
+				v.parseText(fmt.Sprintf("%s.args[%d]", where, j), arg)
+			}
+			envNames := make([]string, 0, len(hook.Env))
+			for name := range hook.Env {
+				envNames = append(envNames, name)
+			}
+			sort.Strings(envNames)
+			for _, name := range envNames {
+				v.parseText(where+".env."+name, hook.Env[name])
+			}
+			v.parseText(where+".workdir", hook.Workdir)
+			v.parseText(where+".when", hook.When)
+		}
+	}
+}
+
+func (v *templateValidator) checkActions() {
+	for i, action := range v.config.Actions {
+		where := fmt.Sprintf("actions[%d]", i)
+		switch action.Type {
+		case "gitInit", "rename", "move", "delete", "appendFile", "jsonPatch", "yamlSet":
+		case "insert":
+			if action.Anchor == "" {
+				v.addProblem("%s: anchor is required", where)
+			}
+			if action.Position != "" && action.Position != "before" && action.Position != "after" {
+				v.addProblem("%s: invalid position %q", where, action.Position)
+			}
+		case "chmod":
+			if _, err := strconv.ParseUint(action.Mode, 8, 32); err != nil {
+				v.addProblem("%s: invalid mode %q", where, action.Mode)
+			}
+		default:
+			v.addProblem("%s: unknown action type %q", where, action.Type)
+		}
+		fields := []struct{ name, text string }{
+			{"when", action.When},
+			{"path", action.Path},
+			{"from", action.From},
+			{"to", action.To},
+			{"content", action.Content},
+			{"anchor", action.Anchor},
+			{"message", action.Message},
+			{"remote", action.Remote},
+		}
+		for _, field := range fields {
+			v.parseText(where+"."+field.name, field.text)
+		}
+	}
+}
+
+func (v *templateValidator) checkLayers() {
+	for i, layer := range v.config.Layers {
+		where := fmt.Sprintf("layers[%d]", i)
+		if _, err := parseLayerConflict(layer.Conflict); err != nil {
+			v.addProblem("%s: %s", where, err)
+		}
+		switch {
+		case strings.TrimSpace(layer.Source) == "":
+			v.addProblem("%s: source is required", where)
+		case isRelativeSource(layer.Source):
+			layerConfig, err := loadProjectConfig(filepath.Join(v.templatePath, filepath.FromSlash(layer.Source)))
+			if err != nil {
+				v.addProblem("%s: %s", where, err)
+				continue
+			}
+			if layerConfig.hasHooks() {
+				v.addProblem("%s: %s", where, errLayerHooks)
+			}
+			for _, prompt := range layerConfig.Prompts {
+				v.layerInputs[prompt.Name] = true
+			}
+		default:
+			v.remoteLayers = true
+		}
+	}
+}
+
+// declaredInputs retorna los inputs definidos por los prompts y por los
+// hooks con output
+func (v *templateValidator) declaredInputs() map[string]bool {
+	declared := make(map[string]bool)
+	for i, prompt := range v.config.Prompts {
+		if prompt.Name == "" {
+			v.addProblem("prompts[%d]: name is required", i)
+			continue
+		}
+		if declared[prompt.Name] {
+			v.addProblem("prompts[%d]: input %s is declared more than once", i, prompt.Name)
+		}
+		declared[prompt.Name] = true
+	}
+	for _, hook := range v.config.outputHooks() {
+		declared[hook.Output] = true
+	}
+	return declared
+}
+
+func (v *templateValidator) checkInputs() {
+	declared := v.declaredInputs()
+	if v.remoteLayers {
+		return
+	}
+	for name := range v.layerInputs {
+		declared[name] = true
+	}
+	var names []string
+	for name := range v.references {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.addProblem("input %s is not declared in prompts (used in %s)", name, strings.Join(v.references[name], ", "))
+	}
+}
+
+func (v *templateValidator) addReference(where string, name string) {
+	for _, existing := range v.references[name] {
+		if existing == where {
+			return
+		}
+	}
+	v.references[name] = append(v.references[name], where)
+}
+
+// collectInputs registra los .Inputs.<name> usados en node. Dentro de range y
+// with el punto deja de ser la raíz, así que ahí solo se consideran las
+// referencias con $.
+func (v *templateValidator) collectInputs(where string, node parse.Node, dotIsRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			v.collectInputs(where, child, dotIsRoot)
+		}
+	case *parse.ActionNode:
+		v.collectInputs(where, n.Pipe, dotIsRoot)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			v.collectInputs(where, n.Pipe, dotIsRoot)
+		}
+	case *parse.IfNode:
+		v.collectBranch(where, &n.BranchNode, dotIsRoot, dotIsRoot)
+	case *parse.RangeNode:
+		v.collectBranch(where, &n.BranchNode, dotIsRoot, false)
+	case *parse.WithNode:
+		v.collectBranch(where, &n.BranchNode, dotIsRoot, false)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, command := range n.Cmds {
+			v.collectInputs(where, command, dotIsRoot)
+		}
+	case *parse.CommandNode:
+		// {{index .Inputs "name"}}
+		if len(n.Args) >= 3 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" && isInputsNode(n.Args[1], dotIsRoot) {
+				if name, ok := n.Args[2].(*parse.StringNode); ok {
+					v.addReference(where, name.Text)
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			v.collectInputs(where, arg, dotIsRoot)
+		}
+	case *parse.FieldNode:
+		if dotIsRoot && len(n.Ident) >= 2 && n.Ident[0] == "Inputs" {
+			v.addReference(where, n.Ident[1])
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) >= 3 && n.Ident[0] == "$" && n.Ident[1] == "Inputs" {
+			v.addReference(where, n.Ident[2])
+		}
+	case *parse.ChainNode:
+		v.collectInputs(where, n.Node, dotIsRoot)
+	}
+}
+
+func (v *templateValidator) collectBranch(where string, n *parse.BranchNode, dotIsRoot bool, bodyDotIsRoot bool) {
+	v.collectInputs(where, n.Pipe, dotIsRoot)
+	v.collectInputs(where, n.List, bodyDotIsRoot)
+	if n.ElseList != nil {
+		v.collectInputs(where, n.ElseList, dotIsRoot)
+	}
+}
+
+// isInputsNode indica si node es .Inputs o $.Inputs
+func isInputsNode(node parse.Node, dotIsRoot bool) bool {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return dotIsRoot && len(n.Ident) == 1 && n.Ident[0] == "Inputs"
+	case *parse.VariableNode:
+		return len(n.Ident) == 2 && n.Ident[0] == "$" && n.Ident[1] == "Inputs"
+	}
+	return false
+}